@@ -3,6 +3,7 @@ module github.com/reggiepy/goutils/v2
 go 1.16
 
 require (
+	github.com/BurntSushi/toml v1.4.0
 	github.com/natefinch/lumberjack v2.0.0+incompatible
 	github.com/stretchr/testify v1.8.1
 	go.uber.org/zap v1.27.0
@@ -10,7 +11,6 @@ require (
 )
 
 require (
-	github.com/BurntSushi/toml v1.4.0 // indirect
 	gopkg.in/natefinch/lumberjack.v2 v2.2.1 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
@@ -1,27 +1,31 @@
 package zaputil
 
-import "encoding/json"
+import (
+	"encoding/json"
+
+	"github.com/reggiepy/goutils/v2/structutil"
+)
 
 type LoggerConfig struct {
-	InFile         bool `json:"InFile" yaml:"InFile"`                 // 是否输出到文件
+	InFile         bool `json:"InFile" yaml:"InFile" default:"true"`  // 是否输出到文件
 	InConsole      bool `json:"InConsole" yaml:"InConsole"`           // 是否输出到终端
 	ReplaceGlobals bool `json:"ReplaceGlobals" yaml:"ReplaceGlobals"` // 是否替换全局日志记录器
 
-	File            string `json:"File" yaml:"File"`                       // 日志文件名
-	MaxSize         int    `json:"MaxSize" yaml:"MaxSize"`                 // 日志文件大小限制（单位：MB）
-	MaxBackups      int    `json:"MaxBackups" yaml:"MaxBackups"`           // 最大保留的旧日志文件数量
-	MaxAge          int    `json:"MaxAge" yaml:"MaxAge"`                   // 旧日志文件保留天数
-	Compress        bool   `json:"Compress" yaml:"Compress"`               // 是否压缩旧日志文件
-	Level           string `json:"LogLevel" yaml:"LogLevel"`               // 日志级别
-	Format          string `json:"LogFormat" yaml:"LogFormat"`             // 日志格式（如：json、logfmt）
-	Caller          bool   `json:"Caller" yaml:"Caller"`                   // 是否显示调用者信息
-	CallerSkip      int    `json:"CallerSkip" yaml:"CallerSkip"`           // 调用者信息跳过的层级
-	StacktraceLevel string `json:"StacktraceLevel" yaml:"StacktraceLevel"` // 堆栈跟踪日志级别
+	File            string `json:"File" yaml:"File" default:"app.log"`                                                                                                                                  // 日志文件名
+	MaxSize         int    `json:"MaxSize" yaml:"MaxSize" default:"1" validate:"min=0"`                                                                                                                 // 日志文件大小限制（单位：MB）
+	MaxBackups      int    `json:"MaxBackups" yaml:"MaxBackups" default:"5" validate:"min=0"`                                                                                                           // 最大保留的旧日志文件数量
+	MaxAge          int    `json:"MaxAge" yaml:"MaxAge" default:"30" validate:"min=0"`                                                                                                                  // 旧日志文件保留天数
+	Compress        bool   `json:"Compress" yaml:"Compress"`                                                                                                                                            // 是否压缩旧日志文件
+	Level           string `json:"LogLevel" yaml:"LogLevel" default:"info" validate:"omitempty,oneof=debug info warn error dpanic panic fatal DEBUG INFO WARN ERROR DPANIC PANIC FATAL"`                // 日志级别
+	Format          string `json:"LogFormat" yaml:"LogFormat" default:"json" validate:"omitempty,oneof=json logfmt"`                                                                                    // 日志格式（如：json、logfmt）
+	Caller          bool   `json:"Caller" yaml:"Caller" default:"true"`                                                                                                                                 // 是否显示调用者信息
+	CallerSkip      int    `json:"CallerSkip" yaml:"CallerSkip" default:"1" validate:"min=0"`                                                                                                           // 调用者信息跳过的层级
+	StacktraceLevel string `json:"StacktraceLevel" yaml:"StacktraceLevel" default:"panic" validate:"omitempty,oneof=debug info warn error dpanic panic fatal DEBUG INFO WARN ERROR DPANIC PANIC FATAL"` // 堆栈跟踪日志级别
 }
 
+// clone 返回配置的深拷贝，修改副本不会影响原配置
 func (l *LoggerConfig) clone() *LoggerConfig {
-	clone := *l
-	return &clone
+	return structutil.Clone(l).(*LoggerConfig)
 }
 
 func (l *LoggerConfig) ToJSON() string {
@@ -36,6 +40,12 @@ func (l *LoggerConfig) LoadJSON(jsonStr string) error {
 	return json.Unmarshal([]byte(jsonStr), l)
 }
 
+// Validate 按照 validate 标签校验配置，返回所有不合法的字段
+// 日志级别与 zapcore.Level 的解析一致，全小写或全大写，JSON Schema 中的枚举相同
+func (l *LoggerConfig) Validate() error {
+	return structutil.Validate(l)
+}
+
 func (l *LoggerConfig) WithOptions(options ...Option) *LoggerConfig {
 	c := l.clone()
 	for _, opt := range options {
@@ -44,22 +54,11 @@ func (l *LoggerConfig) WithOptions(options ...Option) *LoggerConfig {
 	return c
 }
 
-// NewLoggerConfig 创建默认配置
+// NewLoggerConfig 创建默认配置，默认值由字段的 default 标签声明
 func NewLoggerConfig(opts ...Option) *LoggerConfig {
-	config := &LoggerConfig{
-		InFile:          true,      // 默认输出到文件
-		InConsole:       false,     // 默认不输出到控制台
-		File:            "app.log", // 默认日志文件名
-		MaxSize:         1,         // 默认文件大小限制 1MB
-		MaxBackups:      5,         // 默认保留 5 个旧文件
-		MaxAge:          30,        // 默认保留 30 天
-		Compress:        false,     // 默认不压缩
-		Level:           "info",    // 默认日志级别为 info
-		Format:          "json",    // 默认使用 JSON 格式
-		ReplaceGlobals:  false,     // 默认不替换全局 zap logger
-		Caller:          true,      // 默认开启调用者信息
-		CallerSkip:      1,         // 默认跳过 1 层调用栈
-		StacktraceLevel: "panic",   // 默认 panic 级别才打印堆栈信息
+	config := &LoggerConfig{}
+	if err := structutil.SetDefaults(config); err != nil {
+		panic(err)
 	}
 	return config.WithOptions(opts...)
 }
@@ -2,6 +2,7 @@ package zaputil
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -9,9 +10,14 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
+// tempLogFile returns a log file path in a directory removed after the test.
+func tempLogFile(t *testing.T) string {
+	return filepath.Join(t.TempDir(), "app.log")
+}
+
 func TestNewLogger(t *testing.T) {
 	// Test default config
-	config := NewLoggerConfig()
+	config := NewLoggerConfig(WithFile(tempLogFile(t)))
 	logger, cleanup := NewLogger(config)
 	defer cleanup()
 
@@ -21,8 +27,7 @@ func TestNewLogger(t *testing.T) {
 
 func TestNewLoggerWithFile(t *testing.T) {
 	// Temporary file for testing
-	tmpFile := "test_log.log"
-	defer os.Remove(tmpFile)
+	tmpFile := tempLogFile(t)
 
 	config := NewLoggerConfig(
 		WithFile(tmpFile),
@@ -43,7 +48,7 @@ func TestNewLoggerWithFile(t *testing.T) {
 }
 
 func TestNewLoggerWithExtraOptions(t *testing.T) {
-	config := NewLoggerConfig()
+	config := NewLoggerConfig(WithFile(tempLogFile(t)))
 
 	// Use a hook to verify the extra option works
 	var called bool
@@ -78,6 +83,7 @@ func TestLoggerConfig_ToJSON_LoadJSON(t *testing.T) {
 
 func TestNewLoggerWithAdvancedOptions(t *testing.T) {
 	config := NewLoggerConfig(
+		WithFile(tempLogFile(t)),
 		WithCallerSkip(1),
 		WithStacktraceLevel("error"),
 	)
@@ -89,3 +95,37 @@ func TestNewLoggerWithAdvancedOptions(t *testing.T) {
 	assert.Equal(t, 1, config.CallerSkip)
 	assert.Equal(t, "error", config.StacktraceLevel)
 }
+
+func TestLoggerConfig_Validate(t *testing.T) {
+	assert.NoError(t, NewLoggerConfig().Validate())
+
+	config := NewLoggerConfig(WithLogLevel("verbose"))
+	err := config.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Level")
+
+	// Levels are accepted in lower or upper case, as by NewLogger, and an
+	// empty config is accepted too.
+	config = NewLoggerConfig(WithLogLevel("INFO"), WithStacktraceLevel("warn"))
+	assert.NoError(t, config.Validate())
+	assert.Error(t, NewLoggerConfig(WithLogLevel("Warn")).Validate())
+	assert.NoError(t, (&LoggerConfig{}).Validate())
+}
+
+func TestNewLoggerConfigDefaults(t *testing.T) {
+	config := NewLoggerConfig()
+	assert.True(t, config.InFile)
+	assert.False(t, config.InConsole)
+	assert.Equal(t, "app.log", config.File)
+	assert.Equal(t, "info", config.Level)
+	assert.Equal(t, "json", config.Format)
+	assert.Equal(t, "panic", config.StacktraceLevel)
+}
+
+func TestLoggerConfig_WithOptionsCopies(t *testing.T) {
+	config := NewLoggerConfig()
+	derived := config.WithOptions(WithLogLevel("debug"))
+	assert.NotSame(t, config, derived)
+	assert.Equal(t, "info", config.Level)
+	assert.Equal(t, "debug", derived.Level)
+}
@@ -3,23 +3,24 @@ package zlog
 import (
 	"encoding/json"
 
+	"github.com/reggiepy/goutils/v2/structutil"
 	"go.uber.org/zap"
 )
 
 type Config struct {
-	InFile          bool   `json:"InFile" yaml:"InFile"`                   // 是否输出到文件
-	InConsole       bool   `json:"InConsole" yaml:"InConsole"`             // 是否输出到终端
-	ReplaceGlobals  bool   `json:"ReplaceGlobals" yaml:"ReplaceGlobals"`   // 是否替换全局日志记录器
-	File            string `json:"File" yaml:"File"`                       // 日志文件名
-	MaxSize         int    `json:"MaxSize" yaml:"MaxSize"`                 // 日志文件大小限制（单位：MB）
-	MaxBackups      int    `json:"MaxBackups" yaml:"MaxBackups"`           // 最大保留的旧日志文件数量
-	MaxAge          int    `json:"MaxAge" yaml:"MaxAge"`                   // 旧日志文件保留天数
-	Compress        bool   `json:"Compress" yaml:"Compress"`               // 是否压缩旧日志文件
-	Level           string `json:"LogLevel" yaml:"LogLevel"`               // 日志级别
-	Format          string `json:"LogFormat" yaml:"LogFormat"`             // 日志格式（如：json、logfmt）
-	Caller          bool   `json:"Caller" yaml:"Caller"`                   // 是否显示调用者信息
-	CallerSkip      int    `json:"CallerSkip" yaml:"CallerSkip"`           // 调用者信息跳过的层级
-	StacktraceLevel string `json:"StacktraceLevel" yaml:"StacktraceLevel"` // 堆栈跟踪日志级别
+	InFile          bool   `json:"InFile" yaml:"InFile"`                                                                                                                                                // 是否输出到文件
+	InConsole       bool   `json:"InConsole" yaml:"InConsole" default:"true"`                                                                                                                           // 是否输出到终端
+	ReplaceGlobals  bool   `json:"ReplaceGlobals" yaml:"ReplaceGlobals"`                                                                                                                                // 是否替换全局日志记录器
+	File            string `json:"File" yaml:"File" default:"app.log"`                                                                                                                                  // 日志文件名
+	MaxSize         int    `json:"MaxSize" yaml:"MaxSize" default:"1" validate:"min=0"`                                                                                                                 // 日志文件大小限制（单位：MB）
+	MaxBackups      int    `json:"MaxBackups" yaml:"MaxBackups" default:"5" validate:"min=0"`                                                                                                           // 最大保留的旧日志文件数量
+	MaxAge          int    `json:"MaxAge" yaml:"MaxAge" default:"30" validate:"min=0"`                                                                                                                  // 旧日志文件保留天数
+	Compress        bool   `json:"Compress" yaml:"Compress"`                                                                                                                                            // 是否压缩旧日志文件
+	Level           string `json:"LogLevel" yaml:"LogLevel" default:"info" validate:"omitempty,oneof=debug info warn error dpanic panic fatal DEBUG INFO WARN ERROR DPANIC PANIC FATAL"`                // 日志级别
+	Format          string `json:"LogFormat" yaml:"LogFormat" default:"json" validate:"omitempty,oneof=json logfmt"`                                                                                    // 日志格式（如：json、logfmt）
+	Caller          bool   `json:"Caller" yaml:"Caller" default:"true"`                                                                                                                                 // 是否显示调用者信息
+	CallerSkip      int    `json:"CallerSkip" yaml:"CallerSkip" default:"1" validate:"min=0"`                                                                                                           // 调用者信息跳过的层级
+	StacktraceLevel string `json:"StacktraceLevel" yaml:"StacktraceLevel" default:"panic" validate:"omitempty,oneof=debug info warn error dpanic panic fatal DEBUG INFO WARN ERROR DPANIC PANIC FATAL"` // 堆栈跟踪日志级别
 
 	ZapOptions []zap.Option `json:"-" yaml:"-"` // 额外的 zap 选项
 }
@@ -41,24 +42,21 @@ func (l *Config) LoadJSON(jsonStr string) error {
 	return json.Unmarshal([]byte(jsonStr), l)
 }
 
-// NewConfig 创建并返回默认配置对象
+// Validate 按照 validate 标签校验配置，返回所有不合法的字段
+// 日志级别与 zapcore.Level 的解析一致，全小写或全大写，JSON Schema 中的枚举相同
+func (l *Config) Validate() error {
+	return structutil.Validate(l)
+}
+
+// NewConfig 创建并返回默认配置对象，默认值由字段的 default 标签声明
 func NewConfig() *Config {
-	return &Config{
-		InFile:          false,     // 默认不输出到文件
-		InConsole:       true,      // 默认输出到控制台
-		File:            "app.log", // 默认日志文件名
-		MaxSize:         1,         // 默认文件大小限制 1MB
-		MaxBackups:      5,         // 默认保留 5 个旧文件
-		MaxAge:          30,        // 默认保留 30 天
-		Compress:        false,     // 默认不压缩
-		Level:           "info",    // 默认日志级别为 info
-		Format:          "json",    // 默认使用 JSON 格式
-		ReplaceGlobals:  false,     // 默认不替换全局 zap logger
-		Caller:          true,      // 默认开启调用者信息
-		CallerSkip:      1,         // 默认跳过 1 层调用栈
-		StacktraceLevel: "panic",   // 默认 panic 级别才打印堆栈信息
-		ZapOptions:      make([]zap.Option, 0),
+	config := &Config{
+		ZapOptions: make([]zap.Option, 0),
+	}
+	if err := structutil.SetDefaults(config); err != nil {
+		panic(err)
 	}
+	return config
 }
 
 // --- Options ---
@@ -140,12 +138,11 @@ func WithConfig(cfg Config) Option {
 	return func(c *Config) {
 		// 保存原有的 ZapOptions，因为 cfg 中可能没有（如果是从 json 加载的）
 		currentZapOptions := c.ZapOptions
-		*c = cfg
+		// 深拷贝 cfg，避免与调用方共享 ZapOptions 等切片的底层数组
+		*c = structutil.Clone(cfg).(Config)
 
 		// 合并 ZapOptions
-		if len(cfg.ZapOptions) > 0 {
-			c.ZapOptions = cfg.ZapOptions
-		} else {
+		if len(cfg.ZapOptions) == 0 {
 			c.ZapOptions = currentZapOptions
 		}
 	}
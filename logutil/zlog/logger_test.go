@@ -5,6 +5,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/reggiepy/goutils/v2/structutil"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -83,3 +84,64 @@ func TestNewLoggerWithConfigStruct(t *testing.T) {
 	assert.NotNil(t, logger)
 	logger.Warn("Config struct test")
 }
+
+func TestConfigValidate(t *testing.T) {
+	assert.NoError(t, NewConfig().Validate())
+
+	cfg := NewConfig()
+	cfg.Format = "xml"
+	cfg.MaxSize = -1
+	err := cfg.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Format")
+	assert.Contains(t, err.Error(), "MaxSize")
+
+	// Levels are accepted in lower or upper case, as by NewLogger, and an
+	// empty config is accepted too.
+	cfg = NewConfig()
+	cfg.Level, cfg.StacktraceLevel = "INFO", "warn"
+	assert.NoError(t, cfg.Validate())
+	cfg.Level = "Warn"
+	assert.Error(t, cfg.Validate())
+	assert.NoError(t, (&Config{}).Validate())
+}
+
+func TestNewConfigDefaults(t *testing.T) {
+	cfg := NewConfig()
+	assert.False(t, cfg.InFile)
+	assert.True(t, cfg.InConsole)
+	assert.Equal(t, "app.log", cfg.File)
+	assert.Equal(t, 1, cfg.MaxSize)
+	assert.Equal(t, 5, cfg.MaxBackups)
+	assert.Equal(t, 30, cfg.MaxAge)
+	assert.Equal(t, "info", cfg.Level)
+	assert.Equal(t, "json", cfg.Format)
+	assert.True(t, cfg.Caller)
+	assert.Equal(t, 1, cfg.CallerSkip)
+	assert.Equal(t, "panic", cfg.StacktraceLevel)
+	assert.NotNil(t, cfg.ZapOptions)
+}
+
+func TestWithConfigCopiesZapOptions(t *testing.T) {
+	cfg := *NewConfig()
+	cfg.ZapOptions = make([]zap.Option, 1, 4)
+	cfg.ZapOptions[0] = zap.AddCaller()
+
+	c := NewConfig()
+	WithConfig(cfg)(c)
+	WithZapOptions(zap.Development())(c)
+
+	assert.Len(t, c.ZapOptions, 2)
+	assert.Len(t, cfg.ZapOptions, 1)
+	assert.Nil(t, cfg.ZapOptions[:2][1], "WithConfig must not share the caller's backing array")
+}
+
+func TestConfigJSONSchema(t *testing.T) {
+	s, err := structutil.JSONSchema(NewConfig())
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"json", "logfmt"}, s.Properties["LogFormat"].Enum)
+	assert.Equal(t, "info", s.Properties["LogLevel"].Default)
+	assert.Equal(t, s.Properties["LogLevel"].Enum, s.Properties["StacktraceLevel"].Enum)
+	assert.Contains(t, s.Properties["LogLevel"].Enum, "DEBUG")
+	assert.NotContains(t, s.Properties, "ZapOptions")
+}
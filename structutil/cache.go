@@ -0,0 +1,215 @@
+package structutil
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// typeInfo is the reflection metadata structutil needs about a type,
+// computed once per type and shared by all functions in the package.
+type typeInfo struct {
+	isZeroer      bool // implements IsZero() bool
+	isEmptier     bool // T or *T implements IsEmpty() bool
+	textUnmarshal bool // *T implements encoding.TextUnmarshaler
+	marshaler     bool // T or *T implements json.Marshaler or encoding.TextMarshaler
+	equalMethod   int  // index of an `Equal(T) bool` method, or -1
+	cloneMethod   int  // index of a `Clone() T` method, or -1
+
+	// The remaining fields are only set for struct types.
+	fields      []fieldInfo      // direct fields that are exported or embedded structs
+	byName      map[string]int   // index into fields by name
+	promoted    map[string][]int // index paths of fields promoted from embedded structs
+	leaf        bool             // no visible fields; compared and copied as a whole
+	unexported  bool             // has unexported fields, directly or in embedded structs
+	validateErr error            // error parsing validate tags, reported by Validate
+}
+
+// fieldInfo describes a struct field together with its parsed tags.
+type fieldInfo struct {
+	name      string
+	index     []int
+	typ       reflect.Type
+	exported  bool
+	anonymous bool
+	tags      *Tags
+
+	jsonName  string
+	jsonOmit  bool
+	jsonTag   bool // json tag explicitly names the field
+	merge     string
+	sensitive string
+	defValue  string
+	hasDef    bool
+	validate  fieldRules
+}
+
+var typeInfoCache sync.Map // map[reflect.Type]*typeInfo
+
+// cachedType returns the metadata for t, computing it on first use.
+func cachedType(t reflect.Type) *typeInfo {
+	if cached, ok := typeInfoCache.Load(t); ok {
+		return cached.(*typeInfo)
+	}
+	info, _ := typeInfoCache.LoadOrStore(t, newTypeInfo(t))
+	return info.(*typeInfo)
+}
+
+func newTypeInfo(t reflect.Type) *typeInfo {
+	info := &typeInfo{
+		isZeroer:  t.Kind() != reflect.Ptr && t.Implements(isZeroerType),
+		isEmptier: t.Implements(isEmptierType) || t.Kind() != reflect.Ptr && reflect.PtrTo(t).Implements(isEmptierType),
+
+		textUnmarshal: t.Kind() != reflect.Ptr && reflect.PtrTo(t).Implements(textUnmarshalerType),
+		equalMethod:   -1,
+		cloneMethod:   -1,
+	}
+	for _, m := range []reflect.Type{jsonMarshalerType, textMarshalerType} {
+		if t.Implements(m) || t.Kind() != reflect.Ptr && reflect.PtrTo(t).Implements(m) {
+			info.marshaler = true
+		}
+	}
+	if m, ok := t.MethodByName("Equal"); ok && t.Kind() != reflect.Interface {
+		mt := m.Type // includes the receiver
+		if mt.NumIn() == 2 && mt.In(1) == t && mt.NumOut() == 1 && mt.Out(0).Kind() == reflect.Bool {
+			info.equalMethod = m.Index
+		}
+	}
+	if m, ok := t.MethodByName("Clone"); ok && t.Kind() != reflect.Interface {
+		if mt := m.Type; mt.NumIn() == 1 && mt.NumOut() == 1 && mt.Out(0) == t {
+			info.cloneMethod = m.Index
+		}
+	}
+	if t.Kind() != reflect.Struct {
+		return info
+	}
+	info.byName = make(map[string]int)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && (!field.Anonymous || field.Type.Kind() != reflect.Struct || cachedType(field.Type).unexported) {
+			info.unexported = true
+		}
+		if !isVisibleField(field) {
+			continue
+		}
+		tags, _ := ParseTags(string(field.Tag))
+		f := fieldInfo{
+			name:      field.Name,
+			index:     field.Index,
+			typ:       field.Type,
+			exported:  field.PkgPath == "",
+			anonymous: field.Anonymous,
+			tags:      tags,
+		}
+		f.jsonName, f.jsonOmit = jsonFieldName(tags, field.Name)
+		f.jsonTag = f.tag("json") != ""
+		f.merge = f.tag(MergeTagName)
+		f.sensitive = f.tag(SensitiveTagName)
+		f.defValue, f.hasDef = tags.Lookup(DefaultTagName)
+		f.validate.name = field.Name
+		if err := parseValidateTag(f.tag(ValidateTagName), field.Type, &f.validate); err != nil && info.validateErr == nil {
+			info.validateErr = fmt.Errorf("structutil: field %s.%s: %w", t.Name(), field.Name, err)
+		}
+		info.byName[f.name] = len(info.fields)
+		info.fields = append(info.fields, f)
+	}
+	info.leaf = len(info.fields) == 0
+	info.promoted = promotedFields(t)
+	return info
+}
+
+// tag returns the value of the field's tag key, or "".
+func (f *fieldInfo) tag(key string) string {
+	value, _ := f.tags.Lookup(key)
+	return value
+}
+
+// mayHaveMethods is a cheap filter that rules out unnamed non-struct types,
+// such as []string or map[string]int, which cannot have methods.
+func mayHaveMethods(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.PkgPath() != "" || t.Kind() == reflect.Struct
+}
+
+// field looks up a field by name, including fields promoted from embedded
+// structs, and returns a copy with the full index path.
+func (info *typeInfo) field(name string) (fieldInfo, bool) {
+	if i, ok := info.byName[name]; ok {
+		return info.fields[i], true
+	}
+	if index, ok := info.promoted[name]; ok {
+		return fieldInfo{name: name, index: index, exported: true}, true
+	}
+	return fieldInfo{}, false
+}
+
+// promotedFields resolves the exported fields promoted from embedded
+// (non-pointer) structs following Go's rules: shallower fields win and
+// ambiguous names at the same depth are not promoted.
+func promotedFields(t reflect.Type) map[string][]int {
+	type embedded struct {
+		typ    reflect.Type
+		index  []int
+		viaPtr bool // fields behind an embedded pointer may be nil, so only block names
+	}
+	result := make(map[string][]int)
+	blocked := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		blocked[t.Field(i).Name] = true
+	}
+	var current []embedded
+	for i := 0; i < t.NumField(); i++ {
+		if typ, viaPtr, ok := embeddedStruct(t.Field(i)); ok {
+			current = append(current, embedded{typ, t.Field(i).Index, viaPtr})
+		}
+	}
+	visited := map[reflect.Type]bool{t: true}
+	for len(current) > 0 {
+		var next []embedded
+		level := make(map[string][]int)
+		count := make(map[string]int)
+		for _, e := range current {
+			if visited[e.typ] {
+				continue
+			}
+			visited[e.typ] = true
+			for i := 0; i < e.typ.NumField(); i++ {
+				field := e.typ.Field(i)
+				index := append(append([]int(nil), e.index...), i)
+				if !blocked[field.Name] {
+					count[field.Name]++
+					if field.PkgPath == "" && !e.viaPtr {
+						level[field.Name] = index
+					}
+				}
+				if typ, viaPtr, ok := embeddedStruct(field); ok {
+					next = append(next, embedded{typ, index, e.viaPtr || viaPtr})
+				}
+			}
+		}
+		for name, n := range count {
+			blocked[name] = true
+			if index, ok := level[name]; ok && n == 1 {
+				result[name] = index
+			}
+		}
+		current = next
+	}
+	return result
+}
+
+// embeddedStruct returns the struct type embedded by field, if any.
+func embeddedStruct(field reflect.StructField) (reflect.Type, bool, bool) {
+	if !field.Anonymous {
+		return nil, false, false
+	}
+	if field.Type.Kind() == reflect.Struct {
+		return field.Type, false, true
+	}
+	if field.Type.Kind() == reflect.Ptr && field.Type.Elem().Kind() == reflect.Struct {
+		return field.Type.Elem(), true, true
+	}
+	return nil, false, false
+}
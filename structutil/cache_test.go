@@ -0,0 +1,128 @@
+package structutil
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type cacheBase struct {
+	ID string
+}
+
+type cacheShadow struct {
+	ID   int
+	Kind string
+}
+
+type cacheDeep struct {
+	Kind string
+	Deep bool
+}
+
+type cacheOuter struct {
+	cacheBase
+	*cacheShadow
+	cacheDeep
+	Name string
+}
+
+func TestCachedType(t *testing.T) {
+	info := cachedType(reflect.TypeOf(cacheOuter{}))
+	assert.Same(t, info, cachedType(reflect.TypeOf(cacheOuter{})))
+	assert.Len(t, info.fields, 3)
+
+	// Ambiguous names are not promoted, and fields behind embedded
+	// pointers are only reachable through the embedded field.
+	_, ok := info.field("ID")
+	assert.False(t, ok)
+	_, ok = info.field("Kind")
+	assert.False(t, ok)
+	f, ok := info.field("Deep")
+	assert.True(t, ok)
+	assert.Equal(t, []int{2, 1}, f.index)
+	f, ok = info.field("Name")
+	assert.True(t, ok)
+	assert.Equal(t, []int{3}, f.index)
+	_, ok = info.field("cacheShadow")
+	assert.False(t, ok)
+
+	assert.True(t, cachedType(reflect.TypeOf(time.Time{})).isZeroer)
+	assert.True(t, cachedType(reflect.TypeOf(aStruct{})).isEmptier)
+}
+
+func TestCachedTypeConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dst := &aStruct{}
+			CopyIntersectionStruct(&bStruct{Name: "n", Male: "m"}, dst)
+			assert.Equal(t, aStruct{Name: "n", Male: "m"}, *dst)
+		}()
+	}
+	wg.Wait()
+}
+
+// copyIntersectionStructUncached is the lookup-per-call implementation the
+// cache replaced, kept to benchmark against.
+func copyIntersectionStructUncached(src, dst interface{}) {
+	sElement := reflect.ValueOf(src).Elem()
+	dElement := reflect.ValueOf(dst).Elem()
+	for i := 0; i < dElement.NumField(); i++ {
+		dField := dElement.Type().Field(i)
+		sValue := sElement.FieldByName(dField.Name)
+		if !sValue.IsValid() {
+			continue
+		}
+		dElement.Field(i).Set(sValue)
+	}
+}
+
+func BenchmarkCopyIntersectionStruct(b *testing.B) {
+	src := &bStruct{Name: "derrick", Male: "male", Age: 100}
+	dst := &aStruct{}
+	for i := 0; i < b.N; i++ {
+		CopyIntersectionStruct(src, dst)
+	}
+}
+
+func BenchmarkCopyIntersectionStructUncached(b *testing.B) {
+	src := &bStruct{Name: "derrick", Male: "male", Age: 100}
+	dst := &aStruct{}
+	for i := 0; i < b.N; i++ {
+		copyIntersectionStructUncached(src, dst)
+	}
+}
+
+func BenchmarkIsEmptyStringField(b *testing.B) {
+	s := bStruct{Name: "derrick", Male: "male"}
+	for i := 0; i < b.N; i++ {
+		_, _ = IsEmptyStringField(s, "Name", "Male")
+	}
+}
+
+func BenchmarkIsStructEmptyWithTime(b *testing.B) {
+	s := emptyAll{Created: time.Time{}, Inner: emptyInner{}}
+	for i := 0; i < b.N; i++ {
+		IsStructEmpty(s)
+	}
+}
+
+func BenchmarkValidate(b *testing.B) {
+	cfg := validConfig()
+	for i := 0; i < b.N; i++ {
+		_ = Validate(&cfg)
+	}
+}
+
+func BenchmarkDiff(b *testing.B) {
+	x, y := diffFixtures()
+	for i := 0; i < b.N; i++ {
+		_, _ = Diff(x, y)
+	}
+}
@@ -0,0 +1,181 @@
+package structutil
+
+import (
+	"reflect"
+)
+
+// Clone returns a deep copy of v: pointers, slices, maps, arrays and the
+// exported fields of structs, including those promoted from embedded
+// structs of unexported types, are copied recursively, so that modifying the
+// copy never affects v. Shared and cyclic pointers and maps are copied once,
+// preserving the shape of the graph. Nested values with a `Clone() T`
+// method (on T or *T) are copied by calling it; v itself is always copied
+// field by field, so a Clone method may be implemented with Clone.
+// Other unexported fields, channels and functions are copied shallowly.
+// Clone returns a value of the same type as v.
+func Clone(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	c := &cloner{seen: make(map[cloneKey]reflect.Value)}
+	return c.copy(reflect.ValueOf(v), maskNone).Interface()
+}
+
+type cloneKey struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+// cloner deep copies values, optionally masking them for Redact.
+type cloner struct {
+	seen   map[cloneKey]reflect.Value // copies of pointers and maps already visited
+	redact *redactOptions             // nil unless redacting
+}
+
+// clone copies v, preferring its Clone method when there is one.
+func (c *cloner) clone(v reflect.Value, mask int) reflect.Value {
+	if c.redact == nil && mayHaveMethods(v.Type()) {
+		if copied, ok := c.cloneMethod(v); ok {
+			return copied
+		}
+	}
+	return c.copy(v, mask)
+}
+
+func (c *cloner) cloneMethod(v reflect.Value) (reflect.Value, bool) {
+	if i := cachedType(v.Type()).cloneMethod; i >= 0 {
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return v, true
+		}
+		return v.Method(i).Call(nil)[0], true
+	}
+	if v.Kind() == reflect.Ptr {
+		return reflect.Value{}, false
+	}
+	if i := cachedType(reflect.PtrTo(v.Type())).cloneMethod; i >= 0 {
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		copied := ptr.Method(i).Call(nil)[0]
+		if copied.IsNil() {
+			return reflect.Zero(v.Type()), true
+		}
+		return copied.Elem(), true
+	}
+	return reflect.Value{}, false
+}
+
+// copy copies v field by field, masking strings and zeroing values as
+// requested by mask when redacting.
+func (c *cloner) copy(v reflect.Value, mask int) reflect.Value {
+	switch v.Kind() {
+	case reflect.String:
+		if mask != maskNone && v.Len() > 0 {
+			return reflect.ValueOf(c.redact.mask).Convert(v.Type())
+		}
+		return v
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		key := cloneKey{v.Pointer(), v.Type()}
+		if copied, ok := c.seen[key]; ok {
+			return copied
+		}
+		copied := reflect.New(v.Type().Elem())
+		c.seen[key] = copied
+		copied.Elem().Set(c.clone(v.Elem(), mask))
+		return copied
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(c.clone(v.Elem(), mask))
+		return copied
+	case reflect.Struct:
+		info := cachedType(v.Type())
+		if info.leaf {
+			break
+		}
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(v)
+		c.copyFields(copied, v, mask)
+		return copied
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b := append([]byte(nil), v.Bytes()...)
+			if mask != maskNone && len(b) > 0 {
+				b = []byte(c.redact.mask)
+			}
+			return reflect.ValueOf(b).Convert(v.Type())
+		}
+		copied := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			copied.Index(i).Set(c.clone(v.Index(i), mask))
+		}
+		return copied
+	case reflect.Array:
+		copied := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			copied.Index(i).Set(c.clone(v.Index(i), mask))
+		}
+		return copied
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		key := cloneKey{v.Pointer(), v.Type()}
+		if copied, ok := c.seen[key]; ok {
+			return copied
+		}
+		copied := reflect.MakeMapWithSize(v.Type(), v.Len())
+		c.seen[key] = copied
+		iter := v.MapRange()
+		for iter.Next() {
+			elemMask := mask
+			if c.redact != nil && mask == maskNone && iter.Key().Kind() == reflect.String && c.redact.sensitiveName(iter.Key().String()) {
+				elemMask = maskStrings
+			}
+			copied.SetMapIndex(c.clone(iter.Key(), maskNone), c.clone(iter.Value(), elemMask))
+		}
+		return copied
+	}
+	if mask == maskAll {
+		return reflect.Zero(v.Type())
+	}
+	return v
+}
+
+// copyFields replaces the exported fields of dst, a shallow copy of the
+// struct v, by copies. Embedded structs of unexported types cannot be set as
+// a whole, so their fields are replaced one by one.
+func (c *cloner) copyFields(dst, v reflect.Value, mask int) {
+	for _, field := range cachedType(v.Type()).fields {
+		i := field.index[0]
+		switch {
+		case field.exported:
+			dst.Field(i).Set(c.clone(v.Field(i), c.fieldMask(field, mask)))
+		case field.typ.Kind() == reflect.Struct && !cachedType(field.typ).leaf:
+			c.copyFields(dst.Field(i), v.Field(i), c.fieldMask(field, mask))
+		}
+	}
+}
+
+// fieldMask returns the mask for a struct field inside a value masked with mask.
+func (c *cloner) fieldMask(field fieldInfo, mask int) int {
+	if c.redact == nil {
+		return maskNone
+	}
+	switch {
+	case field.sensitive == "true":
+		return maskAll
+	case field.sensitive == "false":
+		return mask
+	case mask == maskNone && c.redact.sensitiveName(field.name):
+		return maskStrings
+	}
+	return mask
+}
@@ -0,0 +1,101 @@
+package structutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type cloneNode struct {
+	Name     string
+	Children []*cloneNode
+	Parent   *cloneNode
+	Labels   map[string][]string
+	Any      interface{}
+	Ports    [2]int
+	Created  time.Time
+	Counter  *cloneCounter
+	Callback func() string
+	hidden   []int
+}
+
+// cloneCounter counts how often its Clone method is called.
+type cloneCounter struct {
+	Calls int
+}
+
+func (c *cloneCounter) Clone() *cloneCounter {
+	return &cloneCounter{Calls: c.Calls + 1}
+}
+
+func TestClone(t *testing.T) {
+	root := &cloneNode{
+		Name:     "root",
+		Labels:   map[string][]string{"env": {"dev"}},
+		Any:      map[string]int{"a": 1},
+		Ports:    [2]int{80, 443},
+		Created:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Counter:  &cloneCounter{},
+		Callback: func() string { return "cb" },
+		hidden:   []int{1},
+	}
+	child := &cloneNode{Name: "child", Parent: root}
+	root.Children = []*cloneNode{child, child}
+
+	copied := Clone(root).(*cloneNode)
+	assert.NotSame(t, root, copied)
+	assert.Equal(t, "root", copied.Name)
+	assert.NotSame(t, child, copied.Children[0])
+	assert.Same(t, copied.Children[0], copied.Children[1])
+	assert.Same(t, copied, copied.Children[0].Parent)
+	assert.Equal(t, root.Created, copied.Created)
+	assert.Equal(t, [2]int{80, 443}, copied.Ports)
+	assert.Equal(t, "cb", copied.Callback())
+	assert.Equal(t, 1, copied.Counter.Calls)
+	assert.Equal(t, []int{1}, copied.hidden)
+
+	copied.Labels["env"][0] = "prod"
+	copied.Any.(map[string]int)["a"] = 2
+	copied.Children[0].Name = "changed"
+	assert.Equal(t, "dev", root.Labels["env"][0])
+	assert.Equal(t, 1, root.Any.(map[string]int)["a"])
+	assert.Equal(t, "child", child.Name)
+
+	// The root value is copied field by field, even with a Clone method.
+	counter := Clone(cloneCounter{Calls: 1}).(cloneCounter)
+	assert.Equal(t, 1, counter.Calls)
+	assert.Equal(t, []string{"a"}, Clone([]string{"a"}))
+	assert.Nil(t, Clone(nil))
+}
+
+func TestCloneCyclicMap(t *testing.T) {
+	m := map[string]interface{}{"a": 1}
+	m["self"] = m
+	copied := Clone(m).(map[string]interface{})
+	copied["a"] = 2
+	assert.Equal(t, 1, m["a"])
+	assert.Equal(t, 2, copied["self"].(map[string]interface{})["a"])
+}
+
+type cloneEmbedded struct {
+	Tags     []string
+	Password string
+}
+
+type cloneOuter struct {
+	cloneEmbedded
+	Name string
+}
+
+func TestCloneEmbeddedUnexported(t *testing.T) {
+	v := cloneOuter{cloneEmbedded{Tags: []string{"a"}, Password: "pw"}, "app"}
+	copied := Clone(v).(cloneOuter)
+	copied.Tags[0] = "b"
+	assert.Equal(t, []string{"a"}, v.Tags)
+	assert.Equal(t, v.Name, copied.Name)
+
+	redacted := Redact(&v).(*cloneOuter)
+	assert.Equal(t, RedactMask, redacted.Password)
+	assert.Equal(t, "pw", v.Password)
+}
@@ -0,0 +1,107 @@
+package structutil
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// DefaultTagName is the struct tag key read by SetDefaults.
+const DefaultTagName = "default"
+
+// SetDefaults fills the empty fields of the struct pointed to by ptr with the
+// values declared in their `default` tags, e.g. `default:"30s"`.
+//
+// Scalars, durations and encoding.TextUnmarshaler implementations are parsed
+// from the tag, slices and arrays from comma separated lists and maps from
+// comma separated key:value pairs. Nested structs, slices of structs and
+// pointers to structs are walked recursively; nil pointers to structs that
+// declare defaults are allocated.
+//
+// Since a false bool is empty, a field tagged `default:"true"` is always set
+// to true: call SetDefaults before applying user supplied values.
+func SetDefaults(ptr interface{}) error {
+	value := reflect.ValueOf(ptr)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("structutil: SetDefaults expects a non-nil struct pointer, got %T", ptr)
+	}
+	return setStructDefaults(value.Elem(), "", map[reflect.Type]int{})
+}
+
+// setStructDefaults walks value's fields; active holds the struct types being
+// walked so that nil pointers of recursive types are not allocated forever.
+func setStructDefaults(value reflect.Value, path string, active map[reflect.Type]int) error {
+	t := value.Type()
+	active[t]++
+	defer func() { active[t]-- }()
+	for _, field := range cachedType(t).fields {
+		fValue := value.Field(field.index[0])
+		fPath := fieldPath(path, field.name)
+		if field.hasDef && field.exported && isEmpty(fValue, defaultEmptyOptions) {
+			if err := setFromString(fValue, field.defValue); err != nil {
+				return fmt.Errorf("structutil: default for %s: %w", fPath, err)
+			}
+			continue
+		}
+		if err := setNestedDefaults(fValue, fPath, active); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setNestedDefaults(v reflect.Value, path string, active map[reflect.Type]int) error {
+	switch v.Kind() {
+	case reflect.Struct:
+		if hasDefaults(v.Type()) {
+			return setStructDefaults(v, path, active)
+		}
+	case reflect.Ptr:
+		if v.IsNil() {
+			if active[v.Type().Elem()] > 0 || !hasDefaults(v.Type().Elem()) {
+				return nil
+			}
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setNestedDefaults(v.Elem(), path, active)
+	case reflect.Slice, reflect.Array:
+		if !hasDefaults(v.Type().Elem()) {
+			return nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := setNestedDefaults(v.Index(i), indexPath(path, i), active); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+var hasDefaultsCache sync.Map // map[reflect.Type]bool
+
+// hasDefaults reports whether t, or a struct reachable from it through
+// fields, pointers, slices and arrays, declares a default tag.
+func hasDefaults(t reflect.Type) bool {
+	if cached, ok := hasDefaultsCache.Load(t); ok {
+		return cached.(bool)
+	}
+	result := searchDefaults(t, map[reflect.Type]bool{})
+	hasDefaultsCache.Store(t, result)
+	return result
+}
+
+func searchDefaults(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || visiting[t] {
+		return false
+	}
+	visiting[t] = true
+	for _, field := range cachedType(t).fields {
+		if field.hasDef || searchDefaults(field.typ, visiting) {
+			return true
+		}
+	}
+	return false
+}
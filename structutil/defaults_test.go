@@ -0,0 +1,77 @@
+package structutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type defaultsDB struct {
+	Host string `default:"localhost"`
+	Port int    `default:"5432"`
+}
+
+type defaultsNode struct {
+	Name string `default:"node"`
+	Next *defaultsNode
+}
+
+type defaultsConfig struct {
+	Name     string            `default:"app"`
+	Enabled  bool              `default:"true"`
+	Ratio    float64           `default:"0.5"`
+	Retries  uint8             `default:"3"`
+	Timeout  time.Duration     `default:"30s"`
+	Started  time.Time         `default:"2024-01-02T03:04:05Z"`
+	Tags     []string          `default:"a, b"`
+	Ports    []int             `default:"80,443"`
+	Labels   map[string]string `default:"env:dev,team:ops"`
+	Level    *string           `default:"info"`
+	DB       defaultsDB
+	Replica  *defaultsDB
+	Replicas []defaultsDB
+	Node     *defaultsNode
+	Untagged *struct{ Name string }
+}
+
+func TestSetDefaults(t *testing.T) {
+	cfg := &defaultsConfig{
+		Name:     "custom",
+		Replicas: []defaultsDB{{Host: "replica"}},
+	}
+	assert.NoError(t, SetDefaults(cfg))
+
+	assert.Equal(t, "custom", cfg.Name)
+	assert.True(t, cfg.Enabled)
+	assert.Equal(t, 0.5, cfg.Ratio)
+	assert.Equal(t, uint8(3), cfg.Retries)
+	assert.Equal(t, 30*time.Second, cfg.Timeout)
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), cfg.Started)
+	assert.Equal(t, []string{"a", "b"}, cfg.Tags)
+	assert.Equal(t, []int{80, 443}, cfg.Ports)
+	assert.Equal(t, map[string]string{"env": "dev", "team": "ops"}, cfg.Labels)
+	if assert.NotNil(t, cfg.Level) {
+		assert.Equal(t, "info", *cfg.Level)
+	}
+	assert.Equal(t, defaultsDB{Host: "localhost", Port: 5432}, cfg.DB)
+	assert.Equal(t, &defaultsDB{Host: "localhost", Port: 5432}, cfg.Replica)
+	assert.Equal(t, []defaultsDB{{Host: "replica", Port: 5432}}, cfg.Replicas)
+	if assert.NotNil(t, cfg.Node) {
+		assert.Equal(t, "node", cfg.Node.Name)
+		assert.Nil(t, cfg.Node.Next)
+	}
+	assert.Nil(t, cfg.Untagged)
+}
+
+func TestSetDefaultsErrors(t *testing.T) {
+	assert.Error(t, SetDefaults(defaultsConfig{}))
+	assert.Error(t, SetDefaults((*defaultsConfig)(nil)))
+
+	type bad struct {
+		Port int `default:"http"`
+	}
+	err := SetDefaults(&bad{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "default for Port")
+}
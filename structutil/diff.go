@@ -0,0 +1,407 @@
+package structutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ChangeKind classifies a Change. The values match RFC 6902 operations.
+type ChangeKind string
+
+const (
+	ChangeAdded    ChangeKind = "add"
+	ChangeRemoved  ChangeKind = "remove"
+	ChangeModified ChangeKind = "replace"
+)
+
+// Change describes a single difference between two values.
+type Change struct {
+	Kind    ChangeKind  `json:"kind"`
+	Path    string      `json:"path"`    // Go path, e.g. "Database.Replicas[1].Host"
+	Pointer string      `json:"pointer"` // JSON pointer using json names, empty if not in JSON
+	Old     interface{} `json:"old,omitempty"`
+	New     interface{} `json:"new,omitempty"`
+}
+
+func (c Change) String() string {
+	path := c.Path
+	if path == "" {
+		path = "." // the compared value itself
+	}
+	switch c.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("+ %s: %s", path, formatValue(c.New))
+	case ChangeRemoved:
+		return fmt.Sprintf("- %s: %s", path, formatValue(c.Old))
+	}
+	return fmt.Sprintf("~ %s: %s -> %s", path, formatValue(c.Old), formatValue(c.New))
+}
+
+// Changes is an ordered list of changes as returned by Diff.
+type Changes []Change
+
+// String renders the changes one per line, prefixed with +, - or ~.
+func (c Changes) String() string {
+	lines := make([]string, len(c))
+	for i, change := range c {
+		lines[i] = change.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+type jsonPatchOp struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// MarshalJSON leaves value out of remove operations only: add and replace
+// need it even when it is null, false, 0 or "".
+func (op jsonPatchOp) MarshalJSON() ([]byte, error) {
+	if op.Op == string(ChangeRemoved) {
+		return json.Marshal(struct {
+			Op   string `json:"op"`
+			Path string `json:"path"`
+		}{op.Op, op.Path})
+	}
+	type plain jsonPatchOp
+	return json.Marshal(plain(op))
+}
+
+// JSONPatch renders the changes as an RFC 6902 JSON Patch document.
+// Changes to fields that are not serialised to JSON are left out.
+func (c Changes) JSONPatch() ([]byte, error) {
+	ops := make([]jsonPatchOp, 0, len(c))
+	for _, change := range c {
+		if change.Pointer == "" && change.Path != "" {
+			continue
+		}
+		op := jsonPatchOp{Op: string(change.Kind), Path: change.Pointer}
+		if change.Kind != ChangeRemoved {
+			op.Value = change.New
+		}
+		ops = append(ops, op)
+	}
+	return json.Marshal(ops)
+}
+
+// Diff compares a and b, which must have the same type, and returns the
+// changes that turn a into b. Structs, pointers, slices, arrays and maps
+// are compared recursively; unexported fields are ignored. []byte values
+// and types with MarshalJSON or MarshalText methods are compared as a
+// whole, as JSON encodes them. Nil and empty slices and maps are considered
+// equal.
+func Diff(a, b interface{}) (Changes, error) {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if !va.IsValid() || !vb.IsValid() {
+		if va.IsValid() || vb.IsValid() {
+			return Changes{{Kind: ChangeModified, Old: a, New: b}}, nil
+		}
+		return nil, nil
+	}
+	if va.Type() != vb.Type() {
+		return nil, fmt.Errorf("structutil: Diff of different types %T and %T", a, b)
+	}
+	d := newDiffer()
+	d.whole = true
+	d.diff(va, vb, "", "", true)
+	return d.changes, nil
+}
+
+// differ walks two values of the same type collecting their differences.
+// Its zero options give Diff's behaviour; Equal makes it stricter or looser.
+type differ struct {
+	changes Changes
+	visited map[visit]bool // pointer pairs being compared, to stop at cycles
+
+	whole          bool // compare values encoded as a whole in JSON as leaves
+	unexported     bool // also compare unexported fields
+	strictNil      bool // nil and empty slices and maps differ
+	unordered      bool // compare slices as multisets
+	floatTolerance float64
+	ignorePaths    []*regexp.Regexp
+	ignoreTags     [][2]string // key, value
+}
+
+type visit struct {
+	a, b uintptr
+	typ  reflect.Type
+}
+
+func newDiffer() *differ {
+	return &differ{visited: make(map[visit]bool)}
+}
+
+// ignored reports whether the value at path, held by field if it is a
+// struct field, is left out of the comparison.
+func (d *differ) ignored(path string, field *fieldInfo) bool {
+	for _, re := range d.ignorePaths {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	if field != nil {
+		for _, tag := range d.ignoreTags {
+			if value, ok := field.tags.Lookup(tag[0]); ok && value == tag[1] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// equal reports whether a and b have no differences under d's options.
+func (d *differ) equal(a, b reflect.Value, path string) bool {
+	sub := *d
+	sub.changes = nil
+	sub.diff(a, b, path, "", false)
+	return len(sub.changes) == 0
+}
+
+// diff appends the changes between a and b to d.changes. inJSON is false
+// below fields that encoding/json skips, which get no Pointer.
+func (d *differ) diff(a, b reflect.Value, path, pointer string, inJSON bool) {
+	add := func(kind ChangeKind, path, pointer string, old, new interface{}) {
+		if !inJSON {
+			pointer = ""
+		}
+		d.changes = append(d.changes, Change{Kind: kind, Path: path, Pointer: pointer, Old: old, New: new})
+	}
+	if d.whole && a.Kind() != reflect.Ptr && a.Kind() != reflect.Interface && encodedWhole(a.Type()) {
+		if !d.wholeEqual(a, b) {
+			add(ChangeModified, path, pointer, interfaceOf(a), interfaceOf(b))
+		}
+		return
+	}
+	switch a.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		switch {
+		case a.IsNil() && b.IsNil():
+		case a.IsNil():
+			add(ChangeAdded, path, pointer, nil, interfaceOf(b))
+		case b.IsNil():
+			add(ChangeRemoved, path, pointer, interfaceOf(a), nil)
+		case a.Elem().Type() != b.Elem().Type():
+			add(ChangeModified, path, pointer, interfaceOf(a), interfaceOf(b))
+		case a.Kind() == reflect.Ptr:
+			key := visit{a.Pointer(), b.Pointer(), a.Type()}
+			if a.Pointer() == b.Pointer() || d.visited[key] {
+				return
+			}
+			d.visited[key] = true
+			defer delete(d.visited, key)
+			d.diff(a.Elem(), b.Elem(), path, pointer, inJSON)
+		default:
+			d.diff(a.Elem(), b.Elem(), path, pointer, inJSON)
+		}
+	case reflect.Struct:
+		info := cachedType(a.Type())
+		if info.leaf {
+			if !d.leafEqual(a, b) {
+				add(ChangeModified, path, pointer, interfaceOf(a), interfaceOf(b))
+			}
+			return
+		}
+		for i := range info.fields {
+			field := &info.fields[i]
+			fPath := fieldPath(path, field.name)
+			if d.ignored(fPath, field) {
+				continue
+			}
+			fPointer := pointer
+			// encoding/json flattens untagged embedded structs into their parent.
+			if !field.anonymous || field.jsonTag {
+				fPointer = pointerPath(pointer, field.jsonName)
+			}
+			j := field.index[0]
+			d.diff(a.Field(j), b.Field(j), fPath, fPointer, inJSON && !field.jsonOmit)
+		}
+		if d.unexported && !unexportedEqual(a, b) {
+			add(ChangeModified, path, "", interfaceOf(a), interfaceOf(b))
+		}
+	case reflect.Slice, reflect.Array:
+		if d.strictNil && a.Kind() == reflect.Slice && a.IsNil() != b.IsNil() {
+			add(ChangeModified, path, pointer, interfaceOf(a), interfaceOf(b))
+			return
+		}
+		if d.unordered && a.Kind() == reflect.Slice {
+			d.diffUnordered(a, b, path, pointer, add)
+			return
+		}
+		n := a.Len()
+		if b.Len() < n {
+			n = b.Len()
+		}
+		for i := 0; i < n; i++ {
+			if iPath := indexPath(path, i); !d.ignored(iPath, nil) {
+				d.diff(a.Index(i), b.Index(i), iPath, pointerPath(pointer, strconv.Itoa(i)), inJSON)
+			}
+		}
+		for i := n; i < b.Len(); i++ {
+			add(ChangeAdded, indexPath(path, i), pointerPath(pointer, strconv.Itoa(i)), nil, interfaceOf(b.Index(i)))
+		}
+		// Remove from the end so that earlier indexes stay valid when applied in order.
+		for i := a.Len() - 1; i >= n; i-- {
+			add(ChangeRemoved, indexPath(path, i), pointerPath(pointer, strconv.Itoa(i)), interfaceOf(a.Index(i)), nil)
+		}
+	case reflect.Map:
+		if d.strictNil && a.IsNil() != b.IsNil() {
+			add(ChangeModified, path, pointer, interfaceOf(a), interfaceOf(b))
+			return
+		}
+		if a.Pointer() == b.Pointer() {
+			return
+		}
+		keys := mergedMapKeys(a, b)
+		for _, key := range keys {
+			kPath := keyPath(path, key)
+			if d.ignored(kPath, nil) {
+				continue
+			}
+			kPointer := pointerPath(pointer, fmt.Sprint(key.Interface()))
+			va, vb := a.MapIndex(key), b.MapIndex(key)
+			switch {
+			case !va.IsValid():
+				add(ChangeAdded, kPath, kPointer, nil, interfaceOf(vb))
+			case !vb.IsValid():
+				add(ChangeRemoved, kPath, kPointer, interfaceOf(va), nil)
+			default:
+				d.diff(va, vb, kPath, kPointer, inJSON)
+			}
+		}
+	case reflect.Float32, reflect.Float64:
+		if !floatsEqual(a.Float(), b.Float(), d.floatTolerance) {
+			add(ChangeModified, path, pointer, interfaceOf(a), interfaceOf(b))
+		}
+	default:
+		if !d.leafEqual(a, b) {
+			add(ChangeModified, path, pointer, interfaceOf(a), interfaceOf(b))
+		}
+	}
+}
+
+// mergedMapKeys returns the union of the keys of a and b in a stable order.
+func mergedMapKeys(a, b reflect.Value) []reflect.Value {
+	seen := make(map[interface{}]bool)
+	var keys []reflect.Value
+	for _, m := range []reflect.Value{a, b} {
+		for _, key := range m.MapKeys() {
+			if !seen[key.Interface()] {
+				seen[key.Interface()] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+	})
+	return keys
+}
+
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// encodedWhole reports whether values of t are encoded in JSON as a single
+// value that JSON Patch cannot address parts of: []byte as base64 and types
+// with MarshalJSON or MarshalText methods.
+func encodedWhole(t reflect.Type) bool {
+	if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+		return true
+	}
+	return mayHaveMethods(t) && cachedType(t).marshaler
+}
+
+// wholeEqual is leafEqual for values that encodedWhole, with nil and empty
+// byte slices equal.
+func (d *differ) wholeEqual(a, b reflect.Value) bool {
+	if a.Kind() == reflect.Slice && a.Type().Elem().Kind() == reflect.Uint8 && !d.strictNil {
+		return bytes.Equal(a.Bytes(), b.Bytes())
+	}
+	return d.leafEqual(a, b)
+}
+
+// leafEqual compares two values of the same type, preferring an
+// `Equal(T) bool` method (as on time.Time) over reflect.DeepEqual.
+func (d *differ) leafEqual(a, b reflect.Value) bool {
+	if !a.CanInterface() {
+		// Only reached through unexported embedded structs, which have no methods to call.
+		return true
+	}
+	if mayHaveMethods(a.Type()) {
+		if i := cachedType(a.Type()).equalMethod; i >= 0 {
+			return a.Method(i).Call([]reflect.Value{b})[0].Bool()
+		}
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+// floatsEqual reports whether x and y differ by at most tolerance; NaNs
+// are equal to each other.
+func floatsEqual(x, y, tolerance float64) bool {
+	if x == y || math.IsNaN(x) && math.IsNaN(y) {
+		return true
+	}
+	return math.Abs(x-y) <= tolerance
+}
+
+func formatValue(v interface{}) string {
+	if rv := reflect.ValueOf(v); (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Map) && rv.IsNil() {
+		return "<nil>"
+	}
+	if s, ok := v.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	return fmt.Sprintf("%+v", v)
+}
+
+// Apply patches the value pointed to by ptr with changes, as produced by
+// Diff, in order. Paths are resolved with the same syntax Diff emits; nil
+// pointers and maps along a path are allocated. The empty path, which Diff
+// uses when whole values differ, replaces the value itself.
+func Apply(ptr interface{}, changes Changes) error {
+	value := reflect.ValueOf(ptr)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return fmt.Errorf("structutil: Apply expects a non-nil pointer, got %T", ptr)
+	}
+	for _, change := range changes {
+		if change.Path == "" {
+			if err := applyRoot(value.Elem(), change); err != nil {
+				return fmt.Errorf("structutil: apply %s to the root: %w", change.Kind, err)
+			}
+			continue
+		}
+		segs, err := parsePath(change.Path)
+		if err != nil {
+			return fmt.Errorf("structutil: %w", err)
+		}
+		if err := updatePath(value.Elem(), segs, applyOp(change)); err != nil {
+			return fmt.Errorf("structutil: apply %s %s: %w", change.Kind, change.Path, err)
+		}
+	}
+	return nil
+}
+
+// applyRoot applies a change with an empty path to v.
+func applyRoot(v reflect.Value, change Change) error {
+	if change.Kind == ChangeRemoved {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	return assignValue(v, change.New)
+}
+
+func applyOp(change Change) pathOp {
+	switch change.Kind {
+	case ChangeRemoved:
+		return removeOp
+	case ChangeAdded:
+		return setOp(change.New, true)
+	}
+	return setOp(change.New, false)
+}
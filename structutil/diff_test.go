@@ -0,0 +1,190 @@
+package structutil
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type diffReplica struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+}
+
+type diffBase struct {
+	ID string `json:"id"`
+}
+
+type diffConfig struct {
+	diffBase
+	Name     string            `json:"name"`
+	Replicas []diffReplica     `json:"replicas"`
+	Labels   map[string]string `json:"labels"`
+	Primary  *diffReplica      `json:"primary,omitempty"`
+	Updated  time.Time         `json:"updated"`
+	Secret   string            `json:"-"`
+}
+
+func diffFixtures() (diffConfig, diffConfig) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	a := diffConfig{
+		diffBase: diffBase{ID: "1"},
+		Name:     "app",
+		Replicas: []diffReplica{{Host: "db1", Port: 5432}, {Host: "db2", Port: 5432}, {Host: "db3", Port: 5432}},
+		Labels:   map[string]string{"env": "dev", "team": "ops"},
+		Updated:  now,
+		Secret:   "a",
+	}
+	b := diffConfig{
+		diffBase: diffBase{ID: "2"},
+		Name:     "app",
+		Replicas: []diffReplica{{Host: "db1", Port: 5433}},
+		Labels:   map[string]string{"env": "prod", "app.kubernetes.io/name": "web"},
+		Primary:  &diffReplica{Host: "db0"},
+		Updated:  now.In(time.FixedZone("CST", 8*3600)),
+		Secret:   "b",
+	}
+	return a, b
+}
+
+func TestDiff(t *testing.T) {
+	a, b := diffFixtures()
+	changes, err := Diff(a, b)
+	assert.NoError(t, err)
+	assert.Equal(t, `~ diffBase.ID: "1" -> "2"
+~ Replicas[0].Port: 5432 -> 5433
+- Replicas[2]: {Host:db3 Port:5432}
+- Replicas[1]: {Host:db2 Port:5432}
++ Labels["app.kubernetes.io/name"]: "web"
+~ Labels[env]: "dev" -> "prod"
+- Labels[team]: "ops"
++ Primary: &{Host:db0 Port:0}
+~ Secret: "a" -> "b"`, changes.String())
+
+	patch, err := changes.JSONPatch()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"op": "replace", "path": "/id", "value": "2"},
+		{"op": "replace", "path": "/replicas/0/port", "value": 5433},
+		{"op": "remove", "path": "/replicas/2"},
+		{"op": "remove", "path": "/replicas/1"},
+		{"op": "add", "path": "/labels/app.kubernetes.io~1name", "value": "web"},
+		{"op": "replace", "path": "/labels/env", "value": "prod"},
+		{"op": "remove", "path": "/labels/team"},
+		{"op": "add", "path": "/primary", "value": {"host": "db0", "port": 0}}
+	]`, string(patch))
+
+	changes, err = Diff(&a, &a)
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+
+	_, err = Diff(a, &b)
+	assert.Error(t, err)
+}
+
+func TestDiffZeroValues(t *testing.T) {
+	type flags struct {
+		Name    string       `json:"name"`
+		Enabled bool         `json:"enabled"`
+		Primary *diffReplica `json:"primary"`
+	}
+	a := flags{Name: "app", Enabled: true, Primary: &diffReplica{Host: "db0"}}
+	changes, err := Diff(a, flags{})
+	assert.NoError(t, err)
+	patch, err := changes.JSONPatch()
+	assert.NoError(t, err)
+	// Replacements with zero values keep their value member.
+	assert.JSONEq(t, `[
+		{"op": "replace", "path": "/name", "value": ""},
+		{"op": "replace", "path": "/enabled", "value": false},
+		{"op": "remove", "path": "/primary"}
+	]`, string(patch))
+
+	// Whole values differ at the root path.
+	changes, err = Diff(1, 0)
+	assert.NoError(t, err)
+	patch, err = changes.JSONPatch()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"op": "replace", "path": "", "value": 0}]`, string(patch))
+	n := 1
+	assert.NoError(t, Apply(&n, changes))
+	assert.Equal(t, 0, n)
+}
+
+func TestApply(t *testing.T) {
+	a, b := diffFixtures()
+	changes, err := Diff(a, b)
+	assert.NoError(t, err)
+
+	assert.NoError(t, Apply(&a, changes))
+	changes, err = Diff(a, b)
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+
+	var empty diffConfig
+	err = Apply(&empty, Changes{
+		{Kind: ChangeAdded, Path: "Replicas[0]", New: diffReplica{Host: "db1"}},
+		{Kind: ChangeAdded, Path: "Replicas[0]", New: diffReplica{Host: "db0"}},
+		{Kind: ChangeModified, Path: "Replicas[1].Port", New: 5432},
+		{Kind: ChangeAdded, Path: "Labels[env]", New: "dev"},
+		{Kind: ChangeModified, Path: "Primary.Host", New: "db9"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []diffReplica{{Host: "db0"}, {Host: "db1", Port: 5432}}, empty.Replicas)
+	assert.Equal(t, map[string]string{"env": "dev"}, empty.Labels)
+	assert.Equal(t, &diffReplica{Host: "db9"}, empty.Primary)
+
+	assert.Error(t, Apply(&empty, Changes{{Kind: ChangeModified, Path: "Missing", New: 1}}))
+	assert.Error(t, Apply(&empty, Changes{{Kind: ChangeModified, Path: "Name", New: 1}}))
+	assert.Error(t, Apply(&empty, Changes{{Kind: ChangeModified, Path: "Replicas[5].Host", New: "x"}}))
+	assert.Error(t, Apply(empty, nil))
+
+	// The empty path replaces or clears the whole value.
+	assert.NoError(t, Apply(&empty, Changes{{Kind: ChangeModified, New: b}}))
+	assert.Equal(t, b, empty)
+	assert.NoError(t, Apply(&empty, Changes{{Kind: ChangeRemoved}}))
+	assert.Equal(t, diffConfig{}, empty)
+}
+
+func TestParsePath(t *testing.T) {
+	segs, err := parsePath(`Database.Replicas[1].Labels["a.b"].Host`)
+	assert.NoError(t, err)
+	assert.Equal(t, []pathSegment{
+		{key: "Database"}, {key: "Replicas"}, {key: "1", bracket: true},
+		{key: "Labels"}, {key: "a.b", bracket: true}, {key: "Host"},
+	}, segs)
+
+	for _, bad := range []string{"", ".A", "A.", "A..B", "A[1", `A["x]`} {
+		_, err := parsePath(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+type diffLevel [2]int
+
+func (l diffLevel) MarshalText() ([]byte, error) {
+	return []byte(strconv.Itoa(l[0]) + "." + strconv.Itoa(l[1])), nil
+}
+
+func TestDiffEncodedWhole(t *testing.T) {
+	type blob struct {
+		Data  []byte    `json:"data"`
+		Level diffLevel `json:"level"`
+		Empty []byte    `json:"empty"`
+	}
+	a := blob{Data: []byte{1, 2}, Level: diffLevel{1, 2}, Empty: []byte{}}
+	b := blob{Data: []byte{1, 99}, Level: diffLevel{1, 3}}
+	changes, err := Diff(a, b)
+	assert.NoError(t, err)
+	patch, err := changes.JSONPatch()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"op": "replace", "path": "/data", "value": "AWM="},
+		{"op": "replace", "path": "/level", "value": "1.3"}
+	]`, string(patch))
+	assert.NoError(t, Apply(&a, changes))
+	assert.Equal(t, b.Data, a.Data)
+	assert.Equal(t, b.Level, a.Level)
+}
@@ -0,0 +1,172 @@
+package structutil
+
+import (
+	"reflect"
+)
+
+// EmptyOption customises how IsZero, IsStructEmpty and NonEmptyFields decide emptiness.
+type EmptyOption func(o *emptyOptions)
+
+type emptyOptions struct {
+	zeroSemantics bool
+	emptyMethod   bool
+}
+
+// WithZeroSemantics switches to reflect.Value.IsZero semantics, so that an
+// allocated but empty slice or map counts as non-empty.
+func WithZeroSemantics() EmptyOption {
+	return func(o *emptyOptions) { o.zeroSemantics = true }
+}
+
+// WithIsEmptyMethod makes values implementing `IsEmpty() bool` decide their own emptiness.
+func WithIsEmptyMethod() EmptyOption {
+	return func(o *emptyOptions) { o.emptyMethod = true }
+}
+
+type isZeroer interface {
+	IsZero() bool
+}
+
+type isEmptier interface {
+	IsEmpty() bool
+}
+
+var defaultEmptyOptions = &emptyOptions{}
+
+func newEmptyOptions(opts []EmptyOption) *emptyOptions {
+	o := &emptyOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// IsZero reports whether v is empty. Unlike reflect.Value.IsZero, empty
+// slices and maps are empty and structs are empty when all their fields
+// are, recursively. Values implementing `IsZero() bool` (such as time.Time)
+// are asked directly. Nil pointers are empty, non-nil pointers are not.
+func IsZero(v interface{}, opts ...EmptyOption) bool {
+	return isEmpty(reflect.ValueOf(v), newEmptyOptions(opts))
+}
+
+// NonEmptyFields returns the paths of the non-empty fields of the struct
+// (or struct pointer) v, descending into nested structs, e.g. "A.Name".
+// It returns nil if v is not a struct or all its fields are empty.
+func NonEmptyFields(v interface{}, opts ...EmptyOption) []string {
+	value := indirectValue(reflect.ValueOf(v))
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+	var paths []string
+	collectNonEmpty(value, "", newEmptyOptions(opts), &paths)
+	return paths
+}
+
+func collectNonEmpty(value reflect.Value, path string, o *emptyOptions, paths *[]string) {
+	t := value.Type()
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		fPath := fieldPath(path, t.Field(i).Name)
+		if isEmpty(field, o) {
+			continue
+		}
+		if field.Kind() == reflect.Struct && !hasEmptinessMethod(field, o) {
+			collectNonEmpty(field, fPath, o, paths)
+			continue
+		}
+		*paths = append(*paths, fPath)
+	}
+}
+
+// indirectValue dereferences pointers until a non-pointer or nil pointer is reached.
+func indirectValue(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v
+}
+
+var (
+	isZeroerType  = reflect.TypeOf((*isZeroer)(nil)).Elem()
+	isEmptierType = reflect.TypeOf((*isEmptier)(nil)).Elem()
+)
+
+// methodReceiver returns a value through which methods with pointer
+// receivers can be called, copying v if it is not addressable.
+func methodReceiver(v reflect.Value) reflect.Value {
+	if v.Kind() == reflect.Ptr {
+		return v
+	}
+	if v.CanAddr() {
+		return v.Addr()
+	}
+	p := reflect.New(v.Type())
+	p.Elem().Set(v)
+	return p
+}
+
+// hasEmptinessMethod reports whether v decides its own emptiness.
+func hasEmptinessMethod(v reflect.Value, o *emptyOptions) bool {
+	if !v.CanInterface() || !mayHaveMethods(v.Type()) {
+		return false
+	}
+	info := cachedType(v.Type())
+	return o.emptyMethod && info.isEmptier || info.isZeroer
+}
+
+func isEmpty(v reflect.Value, o *emptyOptions) bool {
+	if !v.IsValid() {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Chan, reflect.Func:
+		if v.IsNil() {
+			return true
+		}
+	}
+	if v.Kind() == reflect.Interface {
+		return isEmpty(v.Elem(), o)
+	}
+	if v.CanInterface() && mayHaveMethods(v.Type()) {
+		info := cachedType(v.Type())
+		if o.emptyMethod && info.isEmptier {
+			return methodReceiver(v).Interface().(isEmptier).IsEmpty()
+		}
+		if info.isZeroer {
+			return v.Interface().(isZeroer).IsZero()
+		}
+	}
+	if o.zeroSemantics {
+		return v.IsZero()
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Complex64, reflect.Complex128:
+		return v.Complex() == 0
+	case reflect.String, reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if !isEmpty(v.Index(i), o) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if !isEmpty(v.Field(i), o) {
+				return false
+			}
+		}
+		return true
+	}
+	// Non-nil pointers, channels and funcs carry a value.
+	return v.IsZero()
+}
@@ -0,0 +1,82 @@
+package structutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type emptyInner struct {
+	Name string
+}
+
+type emptyAll struct {
+	Enabled  bool
+	Ratio    float64
+	Ptr      *int
+	Labels   map[string]string
+	Any      interface{}
+	Created  time.Time
+	Inner    emptyInner
+	Array    [2]int
+	Callback func()
+}
+
+func TestIsStructEmptyAllKinds(t *testing.T) {
+	one := 1
+	tests := []struct {
+		name string
+		v    interface{}
+		want bool
+	}{
+		{"zero value", emptyAll{}, true},
+		{"bool", emptyAll{Enabled: true}, false},
+		{"float", emptyAll{Ratio: 0.5}, false},
+		{"pointer field", emptyAll{Ptr: &one}, false},
+		{"empty map", emptyAll{Labels: map[string]string{}}, true},
+		{"map", emptyAll{Labels: map[string]string{"a": "b"}}, false},
+		{"empty interface value", emptyAll{Any: ""}, true},
+		{"interface", emptyAll{Any: 1}, false},
+		{"time", emptyAll{Created: time.Now()}, false},
+		{"array", emptyAll{Array: [2]int{0, 1}}, false},
+		{"func", emptyAll{Callback: func() {}}, false},
+		{"pointer input", &emptyAll{}, true},
+		{"nil pointer input", (*emptyAll)(nil), true},
+		{"non-empty pointer input", &emptyAll{Inner: emptyInner{Name: "x"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsStructEmpty(tt.v))
+		})
+	}
+}
+
+func TestIsZeroOptions(t *testing.T) {
+	v := complexSt{S: make([]string, 0)}
+	assert.True(t, IsZero(v))
+	assert.False(t, IsZero(v, WithZeroSemantics()))
+
+	// complexSt.IsEmpty has a pointer receiver and is only consulted on request.
+	v = complexSt{A: aStruct{Name: "x"}}
+	assert.False(t, IsZero(v))
+	assert.False(t, IsZero(v, WithIsEmptyMethod()))
+	assert.True(t, IsZero(aStruct{}, WithIsEmptyMethod()))
+
+	assert.True(t, IsZero(nil))
+	assert.True(t, IsZero(0))
+	assert.False(t, IsZero("a"))
+}
+
+func TestNonEmptyFields(t *testing.T) {
+	v := &emptyAll{
+		Enabled: true,
+		Created: time.Now(),
+		Inner:   emptyInner{Name: "x"},
+		Labels:  map[string]string{},
+	}
+	assert.Equal(t, []string{"Enabled", "Created", "Inner.Name"}, NonEmptyFields(v))
+	assert.Equal(t, []string{"Enabled", "Labels", "Created", "Inner.Name"}, NonEmptyFields(v, WithZeroSemantics()))
+	assert.Nil(t, NonEmptyFields(emptyAll{}))
+	assert.Nil(t, NonEmptyFields("not a struct"))
+}
@@ -0,0 +1,421 @@
+package structutil
+
+import (
+	"encoding"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// EnvTagName is the struct tag key naming a field's environment variable,
+// e.g. `env:"DB"`. `env:"-"` skips the field and the "required" option,
+// `env:"HOST,required"`, makes FromEnv fail when the variable is not set.
+const EnvTagName = "env"
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// ToEnv flattens v into NAME=value pairs, e.g. APP_DB_HOST=localhost for
+// the field DB.Host with the prefix "APP". Names come from `env` tags or
+// are derived from field names in UPPER_SNAKE case; fields of untagged
+// embedded structs are flattened. Slices and maps of scalars are written as
+// comma separated lists (k:v pairs for maps), which fails for items that
+// could not be read back, like "a,b"; slices of structs are written with one
+// variable per element field, e.g. APP_REPLICAS_0_HOST, and maps of structs
+// with one per entry field, e.g. APP_DATABASES_MAIN_HOST for the key
+// "main". Nil pointers are skipped.
+func ToEnv(v interface{}, prefix string) ([]string, error) {
+	var env []string
+	if err := appendEnv(&env, reflect.ValueOf(v), envPrefix(prefix), map[uintptr]bool{}); err != nil {
+		return nil, fmt.Errorf("structutil: %w", err)
+	}
+	return env, nil
+}
+
+// FromEnv sets the fields of the struct pointed to by ptr from the
+// environment variables ToEnv would produce for it with the same prefix.
+// Fields without a variable are left untouched and nil pointers are only
+// allocated when a variable below them is set. Maps of structs are only
+// read for the keys they already hold, since variable names cannot be
+// turned back into keys.
+func FromEnv(ptr interface{}, prefix string) error {
+	return FromEnvLookup(ptr, prefix, os.LookupEnv)
+}
+
+// FromEnvLookup is FromEnv reading variables with lookup instead of os.LookupEnv.
+func FromEnvLookup(ptr interface{}, prefix string, lookup func(string) (string, bool)) error {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("structutil: FromEnv expects a non-nil pointer, got %T", ptr)
+	}
+	r := &envReader{lookup: lookup, active: make(map[reflect.Type]bool)}
+	if _, err := r.read(v.Elem(), envPrefix(prefix), false); err != nil {
+		return fmt.Errorf("structutil: %w", err)
+	}
+	if len(r.missing) > 0 {
+		return fmt.Errorf("structutil: required environment variables not set: %s", strings.Join(r.missing, ", "))
+	}
+	return nil
+}
+
+func envPrefix(prefix string) string {
+	return strings.TrimSuffix(strings.ToUpper(prefix), "_")
+}
+
+// envName joins a variable name and a field's name, reporting false for
+// skipped fields.
+func envName(parent string, field fieldInfo) (string, bool) {
+	if !field.exported && isEnvScalar(field.typ) {
+		return "", false
+	}
+	tag, ok := field.tags.Get(EnvTagName)
+	switch {
+	case ok && tag.Name == "-":
+		return "", false
+	case ok && tag.Name != "":
+		return joinEnv(parent, tag.Name), true
+	case field.anonymous:
+		return parent, true
+	}
+	return joinEnv(parent, UpperSnake(field.name)), true
+}
+
+func joinEnv(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "_" + name
+}
+
+// UpperSnake converts a Go identifier to UPPER_SNAKE case, keeping
+// acronyms and their plurals together: "MaxBackups" -> "MAX_BACKUPS",
+// "APIToken" -> "API_TOKEN", "DBs" -> "DBS".
+func UpperSnake(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			// A word starts at the last capital of an acronym, unless only an
+			// "s" follows it, as in "IDs" or "URLsByHost".
+			plural := i+2 <= len(runes) && runes[i+1] == 's' && (i+2 == len(runes) || !unicode.IsLower(runes[i+2]))
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1]) && !plural
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || unicode.IsUpper(prev) && nextLower {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// isEnvScalar reports whether values of t are stored in a single variable.
+func isEnvScalar(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if mayHaveMethods(t) && (t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType)) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		return cachedType(t).leaf
+	case reflect.Slice, reflect.Array:
+		return isEnvScalar(t.Elem())
+	case reflect.Map:
+		return isEnvScalar(t.Key()) && isEnvScalar(t.Elem())
+	case reflect.Interface:
+		return false
+	}
+	return true
+}
+
+func appendEnv(env *[]string, v reflect.Value, name string, active map[uintptr]bool) error {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		if v.Kind() == reflect.Ptr {
+			if active[v.Pointer()] {
+				return nil
+			}
+			active[v.Pointer()] = true
+			defer delete(active, v.Pointer())
+		}
+		v = v.Elem()
+	}
+	if isEnvScalar(v.Type()) {
+		s, err := formatEnv(v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		*env = append(*env, name+"="+s)
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		for _, field := range cachedType(v.Type()).fields {
+			child, ok := envName(name, field)
+			if !ok {
+				continue
+			}
+			if err := appendEnv(env, v.FieldByIndex(field.index), child, active); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := appendEnv(env, v.Index(i), joinEnv(name, strconv.Itoa(i)), active); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		keys, err := envMapKeys(v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		for _, k := range keys {
+			if err := appendEnv(env, v.MapIndex(k.key), joinEnv(name, k.name), active); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type envMapKey struct {
+	key  reflect.Value
+	name string
+}
+
+// envMapKeys returns the keys of map v with their variable name parts,
+// sorted by name: "main" -> "MAIN", "eu-west" -> "EU_WEST".
+func envMapKeys(v reflect.Value) ([]envMapKey, error) {
+	keys := make([]envMapKey, 0, v.Len())
+	for _, key := range v.MapKeys() {
+		s, err := formatEnv(key)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.Map(func(r rune) rune {
+			if r == '_' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+				return r
+			}
+			return '_'
+		}, UpperSnake(s))
+		keys = append(keys, envMapKey{key, name})
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].name < keys[j].name })
+	return keys, nil
+}
+
+// formatEnv formats a scalar value the way setFromString parses it.
+func formatEnv(v reflect.Value) (string, error) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+	if mayHaveMethods(v.Type()) {
+		if m, ok := interfaceOf(v).(encoding.TextMarshaler); ok {
+			text, err := m.MarshalText()
+			return string(text), err
+		}
+		if reflect.PtrTo(v.Type()).Implements(textMarshalerType) {
+			ptr := reflect.New(v.Type())
+			ptr.Elem().Set(v)
+			text, err := ptr.Interface().(encoding.TextMarshaler).MarshalText()
+			return string(text), err
+		}
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Type() == durationType {
+			return time.Duration(v.Int()).String(), nil
+		}
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes()), nil
+		}
+		items := make([]string, v.Len())
+		for i := range items {
+			s, err := formatEnv(v.Index(i))
+			if err != nil {
+				return "", err
+			}
+			if items[i], err = listItem(s, ","); err != nil {
+				return "", err
+			}
+		}
+		return strings.Join(items, ","), nil
+	case reflect.Map:
+		items := make([]string, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			k, err := formatEnv(iter.Key())
+			if err == nil {
+				k, err = listItem(k, ",:")
+			}
+			if err != nil {
+				return "", err
+			}
+			e, err := formatEnv(iter.Value())
+			if err == nil {
+				e, err = listItem(e, ",")
+			}
+			if err != nil {
+				return "", err
+			}
+			items = append(items, k+":"+e)
+		}
+		sort.Strings(items)
+		return strings.Join(items, ","), nil
+	}
+	return "", fmt.Errorf("cannot format %s as an environment variable", v.Type())
+}
+
+// listItem returns s if it reads back as an item of a comma separated list:
+// without spaces around it nor any of the separators seps.
+func listItem(s, seps string) (string, error) {
+	if strings.ContainsAny(s, seps) || strings.TrimSpace(s) != s {
+		return "", fmt.Errorf("cannot write %q as a list item", s)
+	}
+	return s, nil
+}
+
+type envReader struct {
+	lookup  func(string) (string, bool)
+	missing []string
+	active  map[reflect.Type]bool // pointer types being read, so recursive types are read once
+}
+
+// read sets v from the variables named after name and reports whether any
+// was found. Required variables that are missing are collected in r.missing,
+// unless they belong to a pointer or slice element that is absent altogether.
+func (r *envReader) read(v reflect.Value, name string, required bool) (bool, error) {
+	if isEnvScalar(v.Type()) {
+		s, ok := r.lookup(name)
+		if !ok {
+			if required {
+				r.missing = append(r.missing, name)
+			}
+			return false, nil
+		}
+		if err := setFromString(v, s); err != nil {
+			return true, fmt.Errorf("%s: %w", name, err)
+		}
+		return true, nil
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		if r.active[v.Type()] {
+			return false, nil
+		}
+		r.active[v.Type()] = true
+		defer delete(r.active, v.Type())
+		elem := reflect.New(v.Type().Elem())
+		if !v.IsNil() {
+			elem.Elem().Set(v.Elem())
+		}
+		found, err := r.optional(elem.Elem(), name)
+		if found {
+			v.Set(elem)
+		}
+		return found, err
+	case reflect.Struct:
+		found := false
+		for _, field := range cachedType(v.Type()).fields {
+			child, ok := envName(name, field)
+			if !ok {
+				continue
+			}
+			tag, _ := field.tags.Get(EnvTagName)
+			fieldFound, err := r.read(v.FieldByIndex(field.index), child, tag != nil && tag.HasOption("required"))
+			if err != nil {
+				return true, err
+			}
+			found = found || fieldFound
+		}
+		return found, nil
+	case reflect.Slice:
+		found := false
+		for i := 0; ; i++ {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if i < v.Len() {
+				elem.Set(v.Index(i))
+			}
+			elemFound, err := r.optional(elem, joinEnv(name, strconv.Itoa(i)))
+			if err != nil {
+				return true, err
+			}
+			if !elemFound {
+				if i >= v.Len() {
+					return found, nil
+				}
+				continue
+			}
+			found = true
+			if i < v.Len() {
+				v.Index(i).Set(elem)
+			} else {
+				v.Set(reflect.Append(v, elem))
+			}
+		}
+	case reflect.Array:
+		found := false
+		for i := 0; i < v.Len(); i++ {
+			elemFound, err := r.read(v.Index(i), joinEnv(name, strconv.Itoa(i)), false)
+			if err != nil {
+				return true, err
+			}
+			found = found || elemFound
+		}
+		return found, nil
+	case reflect.Map:
+		keys, err := envMapKeys(v)
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", name, err)
+		}
+		found := false
+		for _, k := range keys {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(v.MapIndex(k.key))
+			elemFound, err := r.optional(elem, joinEnv(name, k.name))
+			if err != nil {
+				return true, err
+			}
+			if elemFound {
+				found = true
+				v.SetMapIndex(k.key, elem)
+			}
+		}
+		return found, nil
+	}
+	return false, nil
+}
+
+// optional reads a value that may be absent as a whole, such as the target
+// of a nil pointer or a slice element; its required variables only count
+// as missing if some of its variables are set.
+func (r *envReader) optional(v reflect.Value, name string) (bool, error) {
+	missing := r.missing
+	found, err := r.read(v, name, false)
+	if !found {
+		r.missing = missing
+	}
+	return found, err
+}
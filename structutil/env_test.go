@@ -0,0 +1,178 @@
+package structutil
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type envReplica struct {
+	Host string `env:"HOST,required"`
+	Port int
+}
+
+type envDatabase struct {
+	Host     string
+	Replicas []envReplica
+	Primary  *envReplica
+}
+
+type envConfig struct {
+	diffBase
+	Name       string
+	APIToken   string
+	DB         envDatabase `env:"DB"`
+	Tags       []string
+	Labels     map[string]int
+	Timeout    time.Duration
+	Started    time.Time
+	MaxBackups *int
+	Next       *envConfig
+	Ignored    string `env:"-"`
+	hidden     string
+}
+
+func TestUpperSnake(t *testing.T) {
+	for in, want := range map[string]string{
+		"Name": "NAME", "MaxBackups": "MAX_BACKUPS", "APIToken": "API_TOKEN",
+		"DBHost": "DB_HOST", "ID": "ID", "HTTP2Port": "HTTP2_PORT", "userID": "USER_ID",
+		"DBs": "DBS", "UserIDs": "USER_IDS", "URLsByHost": "URLS_BY_HOST", "APIsSet": "APIS_SET",
+	} {
+		assert.Equal(t, want, UpperSnake(in), in)
+	}
+}
+
+func TestToEnv(t *testing.T) {
+	backups := 5
+	cfg := envConfig{
+		diffBase: diffBase{ID: "1"},
+		Name:     "app",
+		DB: envDatabase{
+			Host:     "db",
+			Replicas: []envReplica{{Host: "r1", Port: 1}, {Host: "r2"}},
+		},
+		Tags:       []string{"a", "b"},
+		Labels:     map[string]int{"y": 2, "x": 1},
+		Timeout:    5 * time.Second,
+		Started:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		MaxBackups: &backups,
+		Ignored:    "x",
+		hidden:     "x",
+	}
+	cfg.Next = &cfg
+	env, err := ToEnv(&cfg, "app_")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"APP_ID=1",
+		"APP_NAME=app",
+		"APP_API_TOKEN=",
+		"APP_DB_HOST=db",
+		"APP_DB_REPLICAS_0_HOST=r1",
+		"APP_DB_REPLICAS_0_PORT=1",
+		"APP_DB_REPLICAS_1_HOST=r2",
+		"APP_DB_REPLICAS_1_PORT=0",
+		"APP_TAGS=a,b",
+		"APP_LABELS=x:1,y:2",
+		"APP_TIMEOUT=5s",
+		"APP_STARTED=2024-01-01T00:00:00Z",
+		"APP_MAX_BACKUPS=5",
+	}, env)
+}
+
+func TestToEnvListItems(t *testing.T) {
+	for _, v := range []interface{}{
+		struct{ Tags []string }{[]string{"a,b"}},
+		struct{ Tags []string }{[]string{" a"}},
+		struct{ Labels map[string]string }{map[string]string{"a:b": "c"}},
+		struct{ Labels map[string]string }{map[string]string{"a": "b,c"}},
+	} {
+		_, err := ToEnv(v, "")
+		assert.Error(t, err, "%v", v)
+	}
+	env, err := ToEnv(struct{ Labels map[string]string }{map[string]string{"url": "http://a"}}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"LABELS=url:http://a"}, env)
+}
+
+func TestEnvMapOfStructs(t *testing.T) {
+	type config struct {
+		Databases map[string]envReplica
+	}
+	cfg := config{Databases: map[string]envReplica{"main": {Host: "m", Port: 1}, "eu-west": {Host: "e"}}}
+	env, err := ToEnv(cfg, "APP")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"APP_DATABASES_EU_WEST_HOST=e",
+		"APP_DATABASES_EU_WEST_PORT=0",
+		"APP_DATABASES_MAIN_HOST=m",
+		"APP_DATABASES_MAIN_PORT=1",
+	}, env)
+
+	// Only the keys already in the map are read back.
+	vars := map[string]string{"APP_DATABASES_MAIN_HOST": "m2", "APP_DATABASES_MAIN_PORT": "2", "APP_DATABASES_OTHER_HOST": "o"}
+	err = FromEnvLookup(&cfg, "APP", func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]envReplica{"main": {Host: "m2", Port: 2}, "eu-west": {Host: "e"}}, cfg.Databases)
+}
+
+func TestFromEnv(t *testing.T) {
+	vars := map[string]string{
+		"APP_ID":                    "1",
+		"APP_NAME":                  "app",
+		"APP_DB_HOST":               "db",
+		"APP_DB_REPLICAS_0_HOST":    "r1",
+		"APP_DB_REPLICAS_1_HOST":    "r2",
+		"APP_DB_REPLICAS_1_PORT":    "2",
+		"APP_DB_PRIMARY_PORT":       "5432",
+		"APP_TAGS":                  "a, b",
+		"APP_LABELS":                "x:1",
+		"APP_TIMEOUT":               "5s",
+		"APP_STARTED":               "2024-01-01T00:00:00Z",
+		"APP_MAX_BACKUPS":           "5",
+		"APP_NEXT_NAME":             "next",
+		"APP_IGNORED":               "x",
+		"APP_DB_REPLICAS_3_HOST":    "gap",
+		"APP_NEXT_DB_REPLICAS_0_HO": "typo",
+	}
+	lookup := func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+	var cfg envConfig
+	err := FromEnvLookup(&cfg, "APP", lookup)
+	assert.EqualError(t, err, "structutil: required environment variables not set: APP_DB_PRIMARY_HOST")
+
+	vars["APP_DB_PRIMARY_HOST"] = "p"
+	cfg = envConfig{DB: envDatabase{Replicas: []envReplica{{Host: "old", Port: 1}}}}
+	assert.NoError(t, FromEnvLookup(&cfg, "APP", lookup))
+	assert.Equal(t, "1", cfg.ID)
+	assert.Equal(t, "app", cfg.Name)
+	assert.Equal(t, "db", cfg.DB.Host)
+	assert.Equal(t, []envReplica{{Host: "r1", Port: 1}, {Host: "r2", Port: 2}}, cfg.DB.Replicas)
+	assert.Equal(t, &envReplica{Host: "p", Port: 5432}, cfg.DB.Primary)
+	assert.Equal(t, []string{"a", "b"}, cfg.Tags)
+	assert.Equal(t, map[string]int{"x": 1}, cfg.Labels)
+	assert.Equal(t, 5*time.Second, cfg.Timeout)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), cfg.Started)
+	assert.Equal(t, 5, *cfg.MaxBackups)
+	assert.Equal(t, "next", cfg.Next.Name)
+	assert.Nil(t, cfg.Next.Next, "recursive pointer types are followed once")
+	assert.Empty(t, cfg.Ignored)
+
+	vars["APP_TIMEOUT"] = "soon"
+	assert.Error(t, FromEnvLookup(&cfg, "APP", lookup))
+	assert.Error(t, FromEnvLookup(cfg, "APP", lookup))
+}
+
+func TestFromEnvProcess(t *testing.T) {
+	os.Setenv("STRUCTUTIL_TEST_NAME", "from-env")
+	defer os.Unsetenv("STRUCTUTIL_TEST_NAME")
+	var cfg envConfig
+	assert.NoError(t, FromEnv(&cfg, "STRUCTUTIL_TEST"))
+	assert.Equal(t, "from-env", cfg.Name)
+}
@@ -0,0 +1,135 @@
+package structutil
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// EqualOption relaxes or tightens the comparison made by Equal.
+type EqualOption func(d *differ)
+
+// WithIgnoreFields leaves the values at the given paths out of the
+// comparison, e.g. "Updated" or "Replicas[*].ID", where [*] matches any
+// index or map key.
+func WithIgnoreFields(paths ...string) EqualOption {
+	return func(d *differ) {
+		for _, path := range paths {
+			pattern := strings.Replace(regexp.QuoteMeta(path), `\[\*\]`, `\[[^\]]*\]`, -1)
+			d.ignorePaths = append(d.ignorePaths, regexp.MustCompile("^"+pattern+"$"))
+		}
+	}
+}
+
+// WithIgnoreTag leaves out fields whose tag key has the given value,
+// e.g. WithIgnoreTag("json", "-") compares only what is serialised.
+func WithIgnoreTag(key, value string) EqualOption {
+	return func(d *differ) { d.ignoreTags = append(d.ignoreTags, [2]string{key, value}) }
+}
+
+// WithIgnoreUnexported compares exported fields only, as Diff does.
+func WithIgnoreUnexported() EqualOption {
+	return func(d *differ) { d.unexported = false }
+}
+
+// WithFloatTolerance treats floats differing by at most tolerance as equal.
+func WithFloatTolerance(tolerance float64) EqualOption {
+	return func(d *differ) { d.floatTolerance = tolerance }
+}
+
+// WithNilEqualsEmpty treats nil and empty slices and maps as equal.
+func WithNilEqualsEmpty() EqualOption {
+	return func(d *differ) { d.strictNil = false }
+}
+
+// WithUnorderedSlices compares slices regardless of the order of their elements.
+func WithUnorderedSlices() EqualOption {
+	return func(d *differ) { d.unordered = true }
+}
+
+// Equal reports whether a and b are deeply equal and, if not, explains
+// why with one line per difference in the format of Changes.String.
+// Without options it is as strict as reflect.DeepEqual, except that values
+// with an `Equal(T) bool` method such as time.Time are compared with it,
+// NaNs are equal and cyclic pointers are handled. A difference in
+// unexported fields is reported on the struct holding them.
+func Equal(a, b interface{}, opts ...EqualOption) (bool, string) {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if !va.IsValid() || !vb.IsValid() {
+		if va.IsValid() || vb.IsValid() {
+			return false, Change{Kind: ChangeModified, Old: a, New: b}.String()
+		}
+		return true, ""
+	}
+	if va.Type() != vb.Type() {
+		return false, fmt.Sprintf("different types %T and %T", a, b)
+	}
+	d := newDiffer()
+	d.unexported = true
+	d.strictNil = true
+	for _, opt := range opts {
+		opt(d)
+	}
+	d.diff(va, vb, "", "", false)
+	if len(d.changes) > 0 {
+		return false, d.changes.String()
+	}
+	return true, ""
+}
+
+// diffUnordered matches the elements of the slices a and b regardless of
+// their position and reports the unmatched ones as removed and added.
+func (d *differ) diffUnordered(a, b reflect.Value, path, pointer string, add func(kind ChangeKind, path, pointer string, old, new interface{})) {
+	matched := make([]bool, b.Len())
+	var removed []int
+	for i := 0; i < a.Len(); i++ {
+		found := false
+		for j := 0; j < b.Len(); j++ {
+			if !matched[j] && d.equal(a.Index(i), b.Index(j), indexPath(path, i)) {
+				matched[j], found = true, true
+				break
+			}
+		}
+		if !found {
+			removed = append(removed, i)
+		}
+	}
+	for k := len(removed) - 1; k >= 0; k-- {
+		i := removed[k]
+		add(ChangeRemoved, indexPath(path, i), pointerPath(pointer, fmt.Sprint(i)), interfaceOf(a.Index(i)), nil)
+	}
+	for j, ok := range matched {
+		if !ok {
+			add(ChangeAdded, indexPath(path, j), pointerPath(pointer, fmt.Sprint(j)), nil, interfaceOf(b.Index(j)))
+		}
+	}
+}
+
+// unexportedEqual reports whether the structs a and b have equal unexported
+// fields, including those of embedded structs.
+func unexportedEqual(a, b reflect.Value) bool {
+	if !cachedType(a.Type()).unexported || !a.CanInterface() {
+		// Values only reachable through unexported fields are compared by their parent.
+		return true
+	}
+	ca, cb := reflect.New(a.Type()).Elem(), reflect.New(b.Type()).Elem()
+	ca.Set(a)
+	cb.Set(b)
+	zeroExported(ca)
+	zeroExported(cb)
+	return reflect.DeepEqual(ca.Interface(), cb.Interface())
+}
+
+// zeroExported clears the exported fields of the addressable struct v,
+// including those promoted from embedded structs.
+func zeroExported(v reflect.Value) {
+	for _, field := range cachedType(v.Type()).fields {
+		f := v.Field(field.index[0])
+		if field.exported {
+			f.Set(reflect.Zero(f.Type()))
+		} else {
+			zeroExported(f)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package structutil
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type equalPoint struct {
+	ID    string `json:"-"`
+	X, Y  float64
+	Tags  []string
+	Attrs map[string]int
+	note  string
+}
+
+type equalShape struct {
+	equalBase
+	Name    string
+	Points  []equalPoint
+	Created time.Time
+	Next    *equalShape
+}
+
+type equalBase struct {
+	Kind    string
+	version int
+}
+
+func TestEqual(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	a := equalShape{Name: "s", Points: []equalPoint{{ID: "1", X: 1}}, Created: now}
+	b := a
+	b.Points = []equalPoint{{ID: "1", X: 1}}
+	b.Created = now.In(time.FixedZone("CST", 8*3600))
+	ok, diff := Equal(a, b)
+	assert.True(t, ok, diff)
+
+	a.Next, b.Next = &a, &b
+	ok, diff = Equal(&a, &b)
+	assert.True(t, ok, diff)
+	a.Next, b.Next = nil, nil
+
+	b.Points[0].X = 1.0000001
+	b.Points[0].Y = math.NaN()
+	ok, diff = Equal(a, b)
+	assert.False(t, ok)
+	assert.Equal(t, "~ Points[0].X: 1 -> 1.0000001\n~ Points[0].Y: 0 -> NaN", diff)
+	b.Points[0].Y = 0
+	ok, _ = Equal(a, b, WithFloatTolerance(1e-6))
+	assert.True(t, ok)
+}
+
+func TestEqualOptions(t *testing.T) {
+	a := equalShape{
+		equalBase: equalBase{Kind: "poly", version: 1},
+		Points:    []equalPoint{{ID: "1", Tags: []string{"a", "b"}}, {ID: "2", note: "x"}},
+	}
+	b := equalShape{
+		equalBase: equalBase{Kind: "poly", version: 2},
+		Points:    []equalPoint{{ID: "3", Tags: []string{"b", "a"}, Attrs: map[string]int{}}, {ID: "4", note: "y"}},
+	}
+
+	ok, diff := Equal(a, b)
+	assert.False(t, ok)
+	assert.Equal(t, `~ Points[0].ID: "1" -> "3"
+~ Points[0].Tags[0]: "a" -> "b"
+~ Points[0].Tags[1]: "b" -> "a"
+~ Points[0].Attrs: <nil> -> map[]
+~ Points[1].ID: "2" -> "4"
+~ Points[1]: {ID:2 X:0 Y:0 Tags:[] Attrs:map[] note:x} -> {ID:4 X:0 Y:0 Tags:[] Attrs:map[] note:y}
+~ .: {equalBase:{Kind:poly version:1} Name: Points:[{ID:1 X:0 Y:0 Tags:[a b] Attrs:map[] note:} {ID:2 X:0 Y:0 Tags:[] Attrs:map[] note:x}] Created:0001-01-01 00:00:00 +0000 UTC Next:<nil>} -> {equalBase:{Kind:poly version:2} Name: Points:[{ID:3 X:0 Y:0 Tags:[b a] Attrs:map[] note:} {ID:4 X:0 Y:0 Tags:[] Attrs:map[] note:y}] Created:0001-01-01 00:00:00 +0000 UTC Next:<nil>}`, diff)
+
+	ok, diff = Equal(a, b,
+		WithIgnoreTag("json", "-"),
+		WithIgnoreUnexported(),
+		WithNilEqualsEmpty(),
+		WithUnorderedSlices(),
+	)
+	assert.True(t, ok, diff)
+
+	ok, diff = Equal(a, b, WithIgnoreFields("Points[*].ID", "Points[0].Attrs"), WithIgnoreUnexported(), WithUnorderedSlices())
+	assert.True(t, ok, diff)
+
+	ok, diff = Equal([]int{1, 2, 3}, []int{3, 1, 4}, WithUnorderedSlices())
+	assert.False(t, ok)
+	assert.Equal(t, "- [1]: 2\n+ [2]: 4", diff)
+
+	ok, diff = Equal(nil, a)
+	assert.False(t, ok)
+	ok, diff = Equal(a, &b)
+	assert.False(t, ok)
+	assert.Contains(t, diff, "different types")
+	ok, _ = Equal(nil, nil)
+	assert.True(t, ok)
+}
@@ -0,0 +1,287 @@
+package structutil
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// Mapper copies values between different types, such as DTOs and models,
+// matching struct fields by name. Converters registered for a pair of types
+// and field renames registered for a pair of struct types take precedence.
+// Register everything before the first call to Map; Map itself is safe for
+// concurrent use.
+type Mapper struct {
+	converters map[[2]reflect.Type]reflect.Value     // func(S) D or func(S) (D, error) by {S, D}
+	renames    map[[2]reflect.Type]map[string]string // source field path by destination field, by {dst, src}
+}
+
+// NewMapper returns a Mapper without converters or renames.
+func NewMapper() *Mapper {
+	return &Mapper{
+		converters: make(map[[2]reflect.Type]reflect.Value),
+		renames:    make(map[[2]reflect.Type]map[string]string),
+	}
+}
+
+// RegisterConverter registers fn, a func(S) D or func(S) (D, error), to
+// convert every S found in a source into a D, e.g.
+//
+//	m.RegisterConverter(func(t time.Time) string { return t.Format(time.RFC3339) })
+//	m.RegisterConverter(func(s string) (time.Time, error) { return time.Parse(time.RFC3339, s) })
+//
+// A later converter for the same pair of types replaces the earlier one.
+func (m *Mapper) RegisterConverter(fn interface{}) error {
+	fv := reflect.ValueOf(fn)
+	ft := fv.Type()
+	if ft == nil || ft.Kind() != reflect.Func || fv.IsNil() || ft.NumIn() != 1 ||
+		ft.NumOut() < 1 || ft.NumOut() > 2 || ft.NumOut() == 2 && ft.Out(1) != errorType {
+		return fmt.Errorf("structutil: converter must be a func(S) D or func(S) (D, error), got %T", fn)
+	}
+	m.converters[[2]reflect.Type{ft.In(0), ft.Out(0)}] = fv
+	return nil
+}
+
+// RegisterRename maps fields of the struct type of dst from differently
+// named fields of the struct type of src; fields holds the source field for
+// each destination field, e.g. {"CustomerName": "Customer.Name"}. Source
+// fields may be dotted paths through nested structs; nil pointers along the
+// path leave the destination field untouched.
+func (m *Mapper) RegisterRename(dst, src interface{}, fields map[string]string) error {
+	dt, st := structType(reflect.TypeOf(dst)), structType(reflect.TypeOf(src))
+	if dt == nil || st == nil {
+		return fmt.Errorf("structutil: RegisterRename expects structs, got %T and %T", dst, src)
+	}
+	key := [2]reflect.Type{dt, st}
+	renames := m.renames[key]
+	if renames == nil {
+		renames = make(map[string]string, len(fields))
+		m.renames[key] = renames
+	}
+	for dstField, srcField := range fields {
+		if _, ok := cachedType(dt).field(dstField); !ok {
+			return fmt.Errorf("structutil: %s has no field %s", dt, dstField)
+		}
+		if _, ok := fieldTypeByPath(st, srcField); !ok {
+			return fmt.Errorf("structutil: %s has no field %s", st, srcField)
+		}
+		renames[dstField] = srcField
+	}
+	return nil
+}
+
+// Map copies src into the value pointed to by dst. Structs are mapped field
+// by field, slices, arrays and maps element by element and pointers are
+// followed, so whole object graphs can be mapped at once; shared and cyclic
+// pointers are mapped once. For every value, a converter registered for the
+// exact pair of types is used first; otherwise assignable values are copied
+// as they are and numbers are converted when they fit. Destination fields
+// without a matching source field are left untouched.
+func (m *Mapper) Map(dst, src interface{}) error {
+	dv := reflect.ValueOf(dst)
+	if dv.Kind() != reflect.Ptr || dv.IsNil() {
+		return fmt.Errorf("structutil: Map expects a non-nil pointer destination, got %T", dst)
+	}
+	r := &mapping{Mapper: m, seen: make(map[cloneKey]reflect.Value)}
+	if err := r.value(dv.Elem(), reflect.ValueOf(src), ""); err != nil {
+		return fmt.Errorf("structutil: %w", err)
+	}
+	return nil
+}
+
+// mapping is the state of a single Map call.
+type mapping struct {
+	*Mapper
+	seen map[cloneKey]reflect.Value // mapped pointers by source pointer and destination type
+}
+
+func (r *mapping) value(dst, src reflect.Value, path string) error {
+	if !src.IsValid() {
+		return nil
+	}
+	if fn, ok := r.converters[[2]reflect.Type{src.Type(), dst.Type()}]; ok {
+		out := fn.Call([]reflect.Value{src})
+		if len(out) == 2 && !out[1].IsNil() {
+			return fmt.Errorf("%s: %w", mapPath(path), out[1].Interface().(error))
+		}
+		dst.Set(out[0])
+		return nil
+	}
+	switch {
+	case src.Type().AssignableTo(dst.Type()):
+		dst.Set(src)
+		return nil
+	case src.Kind() == reflect.Interface:
+		if src.IsNil() {
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		return r.value(dst, src.Elem(), path)
+	case src.Kind() == reflect.Ptr && src.IsNil():
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	case dst.Kind() == reflect.Ptr:
+		// Map into the existing target, if any, so unmatched fields survive.
+		elem := dst
+		if dst.IsNil() {
+			elem = reflect.New(dst.Type().Elem())
+		}
+		if src.Kind() == reflect.Ptr {
+			key := cloneKey{src.Pointer(), dst.Type()}
+			if mapped, ok := r.seen[key]; ok {
+				dst.Set(mapped)
+				return nil
+			}
+			r.seen[key] = elem
+			src = src.Elem()
+		}
+		if err := r.value(elem.Elem(), src, path); err != nil {
+			return err
+		}
+		dst.Set(elem)
+		return nil
+	case src.Kind() == reflect.Ptr:
+		return r.value(dst, src.Elem(), path)
+	}
+	switch {
+	case dst.Kind() == reflect.Struct && src.Kind() == reflect.Struct:
+		return r.fields(dst, src, path)
+	case dst.Kind() == reflect.Slice && (src.Kind() == reflect.Slice || src.Kind() == reflect.Array):
+		if src.Kind() == reflect.Slice && src.IsNil() {
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		slice := reflect.MakeSlice(dst.Type(), src.Len(), src.Len())
+		for i := 0; i < src.Len(); i++ {
+			if err := r.value(slice.Index(i), src.Index(i), indexPath(path, i)); err != nil {
+				return err
+			}
+		}
+		dst.Set(slice)
+		return nil
+	case dst.Kind() == reflect.Array && (src.Kind() == reflect.Slice || src.Kind() == reflect.Array):
+		if src.Len() > dst.Len() {
+			return fmt.Errorf("%s: %d elements do not fit in %s", mapPath(path), src.Len(), dst.Type())
+		}
+		for i := 0; i < src.Len(); i++ {
+			if err := r.value(dst.Index(i), src.Index(i), indexPath(path, i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case dst.Kind() == reflect.Map && src.Kind() == reflect.Map:
+		if src.IsNil() {
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		m := reflect.MakeMapWithSize(dst.Type(), src.Len())
+		iter := src.MapRange()
+		for iter.Next() {
+			elemPath := keyPath(path, iter.Key())
+			key := reflect.New(dst.Type().Key()).Elem()
+			if err := r.value(key, iter.Key(), elemPath); err != nil {
+				return err
+			}
+			elem := reflect.New(dst.Type().Elem()).Elem()
+			if err := r.value(elem, iter.Value(), elemPath); err != nil {
+				return err
+			}
+			m.SetMapIndex(key, elem)
+		}
+		dst.Set(m)
+		return nil
+	case isNumberKind(dst.Kind()) && isNumberKind(src.Kind()):
+		if err := convertNumber(dst, src); err != nil {
+			return fmt.Errorf("%s: %w", mapPath(path), err)
+		}
+		return nil
+	case dst.Kind() == src.Kind() && src.Type().ConvertibleTo(dst.Type()):
+		// Named types with the same underlying kind, e.g. Status and string.
+		dst.Set(src.Convert(dst.Type()))
+		return nil
+	}
+	return fmt.Errorf("%s: no converter from %s to %s", mapPath(path), src.Type(), dst.Type())
+}
+
+// fields maps the fields of the struct src into the struct dst.
+func (r *mapping) fields(dst, src reflect.Value, path string) error {
+	renames := r.renames[[2]reflect.Type{dst.Type(), src.Type()}]
+	srcInfo := cachedType(src.Type())
+	for _, field := range cachedType(dst.Type()).fields {
+		fieldDst := dst.FieldByIndex(field.index)
+		if srcPath, ok := renames[field.name]; ok {
+			if fieldSrc, ok := fieldByPath(src, srcPath); ok {
+				if err := r.value(fieldDst, fieldSrc, fieldPath(path, field.name)); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if srcField, ok := srcInfo.field(field.name); ok && srcField.exported && field.exported {
+			if err := r.value(fieldDst, src.FieldByIndex(srcField.index), fieldPath(path, field.name)); err != nil {
+				return err
+			}
+			continue
+		}
+		// Fields of an embedded struct missing from src are matched one by one.
+		if field.anonymous && field.typ.Kind() == reflect.Struct {
+			if err := r.fields(fieldDst, src, path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// fieldByPath follows a dotted path of field names from the struct v,
+// reporting false when a field is missing or a pointer on the way is nil.
+func fieldByPath(v reflect.Value, path string) (reflect.Value, bool) {
+	for _, name := range strings.Split(path, ".") {
+		v = indirectValue(v)
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, false
+		}
+		field, ok := cachedType(v.Type()).field(name)
+		if !ok || !field.exported {
+			return reflect.Value{}, false
+		}
+		v = v.FieldByIndex(field.index)
+	}
+	return v, true
+}
+
+// fieldTypeByPath is fieldByPath for types.
+func fieldTypeByPath(t reflect.Type, path string) (reflect.Type, bool) {
+	for _, name := range strings.Split(path, ".") {
+		t = structType(t)
+		if t == nil {
+			return nil, false
+		}
+		field, ok := cachedType(t).field(name)
+		if !ok || !field.exported {
+			return nil, false
+		}
+		t = t.FieldByIndex(field.index).Type
+	}
+	return t, true
+}
+
+// structType dereferences pointer types, returning nil unless t is a struct.
+func structType(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	return t
+}
+
+func mapPath(path string) string {
+	if path == "" {
+		return "."
+	}
+	return path
+}
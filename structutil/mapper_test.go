@@ -0,0 +1,154 @@
+package structutil
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mapStatus int
+
+const (
+	mapActive mapStatus = iota + 1
+	mapClosed
+)
+
+type mapID [4]byte
+
+type mapCustomer struct {
+	Name string
+}
+
+type mapOrderItem struct {
+	SKU      string
+	Quantity int
+}
+
+type mapOrder struct {
+	ID       mapID
+	Customer *mapCustomer
+	Status   mapStatus
+	Created  time.Time
+	Items    []mapOrderItem
+	Notes    map[string]string
+	Parent   *mapOrder
+}
+
+type mapOrderItemDTO struct {
+	SKU      string
+	Quantity int64
+}
+
+type mapOrderDTO struct {
+	ID           string
+	CustomerName string
+	Status       string
+	Created      string
+	Items        []*mapOrderItemDTO
+	Notes        map[string]string
+	Parent       *mapOrderDTO
+	Extra        string
+}
+
+func newOrderMapper(t *testing.T) *Mapper {
+	m := NewMapper()
+	assert.NoError(t, m.RegisterConverter(func(t time.Time) string { return t.Format(time.RFC3339) }))
+	assert.NoError(t, m.RegisterConverter(func(s string) (time.Time, error) { return time.Parse(time.RFC3339, s) }))
+	assert.NoError(t, m.RegisterConverter(func(id mapID) string { return fmt.Sprintf("%x", id[:]) }))
+	assert.NoError(t, m.RegisterConverter(func(s mapStatus) string {
+		return map[mapStatus]string{mapActive: "active", mapClosed: "closed"}[s]
+	}))
+	assert.NoError(t, m.RegisterRename(mapOrderDTO{}, mapOrder{}, map[string]string{"CustomerName": "Customer.Name"}))
+	return m
+}
+
+func TestMapper(t *testing.T) {
+	m := newOrderMapper(t)
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	order := &mapOrder{
+		ID:       mapID{0xde, 0xad, 0xbe, 0xef},
+		Customer: &mapCustomer{Name: "Ada"},
+		Status:   mapActive,
+		Created:  created,
+		Items:    []mapOrderItem{{SKU: "a", Quantity: 1}, {SKU: "b", Quantity: 2}},
+		Notes:    map[string]string{"gift": "yes"},
+	}
+	order.Parent = order
+
+	dto := mapOrderDTO{Extra: "kept"}
+	assert.NoError(t, m.Map(&dto, order))
+	assert.Equal(t, "deadbeef", dto.ID)
+	assert.Equal(t, "Ada", dto.CustomerName)
+	assert.Equal(t, "active", dto.Status)
+	assert.Equal(t, "2024-05-01T12:00:00Z", dto.Created)
+	assert.Equal(t, []*mapOrderItemDTO{{SKU: "a", Quantity: 1}, {SKU: "b", Quantity: 2}}, dto.Items)
+	assert.Equal(t, order.Notes, dto.Notes)
+	assert.Equal(t, "kept", dto.Extra)
+	// The cycle is preserved rather than followed forever.
+	assert.Same(t, dto.Parent, dto.Parent.Parent)
+	assert.Equal(t, "deadbeef", dto.Parent.ID)
+
+	// A nil pointer along a renamed path leaves the field untouched.
+	dto = mapOrderDTO{CustomerName: "unknown"}
+	assert.NoError(t, m.Map(&dto, mapOrder{Created: created}))
+	assert.Equal(t, "unknown", dto.CustomerName)
+	assert.Nil(t, dto.Items)
+
+	// Slices of structs map directly too.
+	var items []mapOrderItemDTO
+	assert.NoError(t, m.Map(&items, order.Items))
+	assert.Equal(t, []mapOrderItemDTO{{SKU: "a", Quantity: 1}, {SKU: "b", Quantity: 2}}, items)
+}
+
+func TestMapperReverse(t *testing.T) {
+	m := newOrderMapper(t)
+	assert.NoError(t, m.RegisterConverter(func(s string) (mapStatus, error) {
+		switch s {
+		case "active":
+			return mapActive, nil
+		case "closed":
+			return mapClosed, nil
+		}
+		return 0, errors.New("unknown status " + s)
+	}))
+	assert.NoError(t, m.RegisterConverter(func(s string) (mapID, error) {
+		var id mapID
+		b, err := hex.DecodeString(s)
+		copy(id[:], b)
+		return id, err
+	}))
+
+	var order mapOrder
+	dto := mapOrderDTO{Status: "closed", Created: "2024-05-01T12:00:00Z", Items: []*mapOrderItemDTO{{SKU: "a", Quantity: 3}}}
+	assert.NoError(t, m.Map(&order, dto))
+	assert.Equal(t, mapClosed, order.Status)
+	assert.Equal(t, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), order.Created)
+	assert.Equal(t, []mapOrderItem{{SKU: "a", Quantity: 3}}, order.Items)
+
+	dto.Status = "open"
+	err := m.Map(&order, dto)
+	assert.EqualError(t, err, "structutil: Status: unknown status open")
+
+	dto = mapOrderDTO{Created: "2024-05-01T12:00:00Z", Items: []*mapOrderItemDTO{{Quantity: 1 << 40}}}
+	var small struct{ Items []struct{ Quantity int8 } }
+	assert.EqualError(t, m.Map(&small, dto), "structutil: Items[0].Quantity: 1099511627776 does not fit in int8")
+
+	// There is no converter from string to bool.
+	var flags struct{ Status bool }
+	assert.EqualError(t, m.Map(&flags, dto), "structutil: Status: no converter from string to bool")
+}
+
+func TestMapperRegisterErrors(t *testing.T) {
+	m := NewMapper()
+	assert.Error(t, m.RegisterConverter("not a func"))
+	assert.Error(t, m.RegisterConverter(func(a, b int) int { return a + b }))
+	assert.Error(t, m.RegisterConverter(func(int) (int, int) { return 0, 0 }))
+	assert.Error(t, m.RegisterRename(mapOrderDTO{}, mapOrder{}, map[string]string{"Missing": "Status"}))
+	assert.Error(t, m.RegisterRename(mapOrderDTO{}, mapOrder{}, map[string]string{"Status": "Customer.Missing"}))
+	assert.Error(t, m.RegisterRename(1, mapOrder{}, nil))
+	assert.Error(t, m.Map(mapOrderDTO{}, mapOrder{}))
+}
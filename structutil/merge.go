@@ -0,0 +1,148 @@
+package structutil
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// MergeTagName is the struct tag key read by Merge for per-field strategies.
+const MergeTagName = "merge"
+
+// Per-field merge strategies, set with e.g. `merge:"replace"`.
+const (
+	MergeReplace = "replace" // replace the value as a whole, never merge into it
+	MergeAppend  = "append"  // append slices
+	MergeDeep    = "deep"    // merge maps key by key
+	MergeSkip    = "-"       // never merge the field
+)
+
+// MergeOption customises how Merge combines values.
+type MergeOption func(o *mergeOptions)
+
+type mergeOptions struct {
+	override     bool
+	appendSlices bool
+	deepMaps     bool
+}
+
+// WithOverride makes empty source values overwrite the destination too.
+// By default only non-empty source values are merged.
+func WithOverride() MergeOption {
+	return func(o *mergeOptions) { o.override = true }
+}
+
+// WithAppendSlices appends source slices to destination slices instead of replacing them.
+func WithAppendSlices() MergeOption {
+	return func(o *mergeOptions) { o.appendSlices = true }
+}
+
+// WithDeepMergeMaps merges maps key by key, recursing into values, instead of replacing them.
+func WithDeepMergeMaps() MergeOption {
+	return func(o *mergeOptions) { o.deepMaps = true }
+}
+
+// Merge merges src into the struct pointed to by dst. Both must have the
+// same type; src may be a struct or a struct pointer. Nested structs and
+// pointers to structs are merged field by field, while slices and maps are
+// replaced unless WithAppendSlices or WithDeepMergeMaps (or the field's
+// `merge` tag) says otherwise. Only non-empty source values are merged
+// unless WithOverride is given, so layering defaults, files, environment
+// and flags is a matter of merging each layer in turn.
+func Merge(dst, src interface{}, opts ...MergeOption) error {
+	dv := reflect.ValueOf(dst)
+	if dv.Kind() != reflect.Ptr || dv.IsNil() {
+		return fmt.Errorf("structutil: Merge expects a non-nil pointer destination, got %T", dst)
+	}
+	sv := reflect.ValueOf(src)
+	if sv.Kind() == reflect.Ptr && sv.Type() == dv.Type() {
+		if sv.IsNil() {
+			return nil
+		}
+		sv = sv.Elem()
+	}
+	if sv.Type() != dv.Elem().Type() {
+		return fmt.Errorf("structutil: Merge of different types %T and %T", dst, src)
+	}
+	o := &mergeOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	mergeValue(dv.Elem(), sv, o, "")
+	return nil
+}
+
+func mergeValue(dst, src reflect.Value, o *mergeOptions, strategy string) {
+	if !o.override && isEmpty(src, defaultEmptyOptions) {
+		return
+	}
+	switch dst.Kind() {
+	case reflect.Struct:
+		info := cachedType(dst.Type())
+		if strategy == MergeReplace || info.leaf {
+			break
+		}
+		for _, field := range info.fields {
+			fStrategy := field.merge
+			if fStrategy == MergeSkip {
+				continue
+			}
+			if !field.exported {
+				// Embedded unexported structs can only be merged field by field.
+				if cachedType(field.typ).leaf {
+					continue
+				}
+				fStrategy = ""
+			}
+			i := field.index[0]
+			mergeValue(dst.Field(i), src.Field(i), o, fStrategy)
+		}
+		return
+	case reflect.Ptr:
+		if strategy == MergeReplace || src.IsNil() || src.Elem().Kind() != reflect.Struct {
+			break
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		mergeValue(dst.Elem(), src.Elem(), o, "")
+		return
+	case reflect.Interface:
+		if strategy == MergeReplace || dst.IsNil() || src.IsNil() || dst.Elem().Type() != src.Elem().Type() {
+			break
+		}
+		elem := reflect.New(dst.Elem().Type()).Elem()
+		elem.Set(dst.Elem())
+		mergeValue(elem, src.Elem(), o, strategy)
+		dst.Set(elem)
+		return
+	case reflect.Slice:
+		if strategy != MergeReplace && (strategy == MergeAppend || o.appendSlices) {
+			dst.Set(reflect.AppendSlice(dst, src))
+			return
+		}
+	case reflect.Map:
+		if strategy != MergeReplace && (strategy == MergeDeep || o.deepMaps) && !src.IsNil() {
+			mergeMap(dst, src, o)
+			return
+		}
+	}
+	dst.Set(src)
+}
+
+func mergeMap(dst, src reflect.Value, o *mergeOptions) {
+	if dst.IsNil() {
+		dst.Set(reflect.MakeMapWithSize(dst.Type(), src.Len()))
+	}
+	iter := src.MapRange()
+	for iter.Next() {
+		existing := dst.MapIndex(iter.Key())
+		if !existing.IsValid() {
+			dst.SetMapIndex(iter.Key(), iter.Value())
+			continue
+		}
+		elem := reflect.New(dst.Type().Elem()).Elem()
+		elem.Set(existing)
+		mergeValue(elem, iter.Value(), o, MergeDeep)
+		dst.SetMapIndex(iter.Key(), elem)
+	}
+}
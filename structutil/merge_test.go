@@ -0,0 +1,94 @@
+package structutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mergeDB struct {
+	Host string
+	Port int
+}
+
+type mergeConfig struct {
+	Name     string
+	Debug    bool
+	DB       mergeDB
+	Replica  *mergeDB
+	Tags     []string
+	Plugins  []string `merge:"append"`
+	Labels   map[string]string
+	Extra    map[string]interface{}
+	Override map[string]string `merge:"replace"`
+	Internal string            `merge:"-"`
+}
+
+func TestMerge(t *testing.T) {
+	dst := mergeConfig{
+		Name:     "defaults",
+		DB:       mergeDB{Host: "localhost", Port: 5432},
+		Tags:     []string{"a"},
+		Plugins:  []string{"auth"},
+		Labels:   map[string]string{"env": "dev", "team": "ops"},
+		Extra:    map[string]interface{}{"limits": map[string]interface{}{"cpu": 1, "mem": 2}},
+		Override: map[string]string{"a": "1"},
+	}
+	src := &mergeConfig{
+		Debug:    true,
+		DB:       mergeDB{Port: 6432},
+		Replica:  &mergeDB{Host: "replica"},
+		Tags:     []string{"b"},
+		Plugins:  []string{"metrics"},
+		Labels:   map[string]string{"env": "prod"},
+		Extra:    map[string]interface{}{"limits": map[string]interface{}{"cpu": 4}},
+		Override: map[string]string{"b": "2"},
+		Internal: "ignored",
+	}
+
+	assert.NoError(t, Merge(&dst, src))
+	assert.Equal(t, "defaults", dst.Name)
+	assert.True(t, dst.Debug)
+	assert.Equal(t, mergeDB{Host: "localhost", Port: 6432}, dst.DB)
+	assert.Equal(t, &mergeDB{Host: "replica"}, dst.Replica)
+	assert.NotSame(t, src.Replica, dst.Replica)
+	assert.Equal(t, []string{"b"}, dst.Tags)
+	assert.Equal(t, []string{"auth", "metrics"}, dst.Plugins)
+	assert.Equal(t, map[string]string{"env": "prod"}, dst.Labels)
+	assert.Equal(t, map[string]string{"b": "2"}, dst.Override)
+	assert.Empty(t, dst.Internal)
+}
+
+func TestMergeOptions(t *testing.T) {
+	dst := mergeConfig{
+		Name:     "defaults",
+		Tags:     []string{"a"},
+		Labels:   map[string]string{"env": "dev", "team": "ops"},
+		Extra:    map[string]interface{}{"limits": map[string]interface{}{"cpu": 1, "mem": 2}},
+		Override: map[string]string{"a": "1"},
+	}
+	src := mergeConfig{
+		Tags:     []string{"b"},
+		Labels:   map[string]string{"env": "prod"},
+		Extra:    map[string]interface{}{"limits": map[string]interface{}{"cpu": 4}},
+		Override: map[string]string{"b": "2"},
+	}
+
+	assert.NoError(t, Merge(&dst, src, WithAppendSlices(), WithDeepMergeMaps()))
+	assert.Equal(t, "defaults", dst.Name)
+	assert.Equal(t, []string{"a", "b"}, dst.Tags)
+	assert.Equal(t, map[string]string{"env": "prod", "team": "ops"}, dst.Labels)
+	assert.Equal(t, map[string]interface{}{"limits": map[string]interface{}{"cpu": 4, "mem": 2}}, dst.Extra)
+	assert.Equal(t, map[string]string{"b": "2"}, dst.Override)
+
+	assert.NoError(t, Merge(&dst, mergeConfig{Tags: []string{"c"}}, WithOverride()))
+	assert.Empty(t, dst.Name)
+	assert.Equal(t, []string{"c"}, dst.Tags)
+	assert.Nil(t, dst.Labels)
+}
+
+func TestMergeErrors(t *testing.T) {
+	assert.Error(t, Merge(mergeConfig{}, mergeConfig{}))
+	assert.Error(t, Merge(&mergeConfig{}, mergeDB{}))
+	assert.NoError(t, Merge(&mergeConfig{}, (*mergeConfig)(nil)))
+}
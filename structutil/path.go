@@ -0,0 +1,575 @@
+package structutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// fieldPath appends a struct field name to a dotted path.
+func fieldPath(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+// indexPath appends a slice or array index to a path, e.g. "Replicas[1]".
+func indexPath(parent string, i int) string {
+	return parent + "[" + strconv.Itoa(i) + "]"
+}
+
+// keyPath appends a map key to a path, e.g. "Labels[env]".
+// Keys containing path syntax characters are quoted.
+func keyPath(parent string, key reflect.Value) string {
+	k := fmt.Sprint(key.Interface())
+	if k == "" || strings.ContainsAny(k, `.[]"`) {
+		k = strconv.Quote(k)
+	}
+	return parent + "[" + k + "]"
+}
+
+// pointerPath appends a token to a JSON pointer (RFC 6901).
+func pointerPath(parent, token string) string {
+	token = strings.Replace(token, "~", "~0", -1)
+	token = strings.Replace(token, "/", "~1", -1)
+	return parent + "/" + token
+}
+
+// jsonFieldName returns the name encoding/json uses for a field with the
+// given tags, and whether the field is omitted from JSON output altogether.
+func jsonFieldName(tags *Tags, name string) (string, bool) {
+	tag, ok := tags.Get("json")
+	if !ok {
+		return name, false
+	}
+	if tag.Name == "-" && len(tag.Options) == 0 {
+		return "", true
+	}
+	if tag.Name == "" {
+		return name, false
+	}
+	return tag.Name, false
+}
+
+// pathSegment is one step of a path: a struct field or map key written as
+// ".Name", or an index or map key written as "[key]".
+type pathSegment struct {
+	key     string
+	bracket bool
+}
+
+func (s pathSegment) String() string {
+	if s.bracket {
+		return "[" + s.key + "]"
+	}
+	return s.key
+}
+
+// parsePath splits a path such as `Database.Replicas[1].Host` or
+// `Labels["app.kubernetes.io/name"]` into segments.
+func parsePath(path string) ([]pathSegment, error) {
+	var segs []pathSegment
+	for i := 0; i < len(path); {
+		switch path[i] {
+		case '.':
+			if i == 0 || i == len(path)-1 {
+				return nil, fmt.Errorf("invalid path %q", path)
+			}
+			i++
+		case '[':
+			end, key, err := parseBracket(path, i)
+			if err != nil {
+				return nil, err
+			}
+			segs = append(segs, pathSegment{key: key, bracket: true})
+			i = end
+			continue
+		}
+		j := i
+		for j < len(path) && path[j] != '.' && path[j] != '[' {
+			j++
+		}
+		if j == i {
+			if j < len(path) && path[j] == '[' {
+				continue
+			}
+			return nil, fmt.Errorf("invalid path %q: empty segment", path)
+		}
+		segs = append(segs, pathSegment{key: path[i:j]})
+		i = j
+	}
+	if len(segs) == 0 {
+		return nil, fmt.Errorf("invalid path %q", path)
+	}
+	return segs, nil
+}
+
+// parseBracket parses the bracketed segment starting at path[start] and
+// returns the index just past it together with the unquoted key.
+func parseBracket(path string, start int) (int, string, error) {
+	if start+1 < len(path) && path[start+1] == '"' {
+		for j := start + 2; j < len(path); j++ {
+			switch path[j] {
+			case '\\':
+				j++
+			case '"':
+				if j+1 >= len(path) || path[j+1] != ']' {
+					return 0, "", fmt.Errorf("invalid path %q: expected ] after quoted key", path)
+				}
+				key, err := strconv.Unquote(path[start+1 : j+1])
+				if err != nil {
+					return 0, "", fmt.Errorf("invalid path %q: %w", path, err)
+				}
+				return j + 2, key, nil
+			}
+		}
+		return 0, "", fmt.Errorf("invalid path %q: unterminated quoted key", path)
+	}
+	end := strings.IndexByte(path[start:], ']')
+	if end < 0 {
+		return 0, "", fmt.Errorf("invalid path %q: missing ]", path)
+	}
+	return start + end + 1, path[start+1 : start+end], nil
+}
+
+// sliceIndex converts seg to an index into a slice or array of length n.
+func sliceIndex(seg pathSegment, n int, allowEnd bool) (int, error) {
+	i, err := strconv.Atoi(seg.key)
+	if err != nil {
+		return 0, fmt.Errorf("invalid index %q", seg.key)
+	}
+	if i < 0 || i > n || i == n && !allowEnd {
+		return 0, fmt.Errorf("index %d out of range [0:%d]", i, n)
+	}
+	return i, nil
+}
+
+// mapKey converts seg to a key of the map type t.
+func mapKey(seg pathSegment, t reflect.Type) (reflect.Value, error) {
+	key := reflect.New(t.Key()).Elem()
+	if err := setFromString(key, seg.key); err != nil {
+		return reflect.Value{}, fmt.Errorf("invalid map key %q: %w", seg.key, err)
+	}
+	return key, nil
+}
+
+// isVisibleField reports whether field is exported, or is an embedded
+// struct whose exported fields are promoted.
+func isVisibleField(field reflect.StructField) bool {
+	return field.PkgPath == "" || field.Anonymous && field.Type.Kind() == reflect.Struct
+}
+
+// structField looks up the exported field named by seg.
+func structField(v reflect.Value, seg pathSegment) (reflect.Value, error) {
+	if field, ok := cachedType(v.Type()).field(seg.key); ok {
+		return v.FieldByIndex(field.index), nil
+	}
+	return reflect.Value{}, fmt.Errorf("no field %q in %s", seg.key, v.Type())
+}
+
+// settableField is structField for a field about to be changed; embedded
+// structs of unexported types can be walked into but not set.
+func settableField(v reflect.Value, seg pathSegment) (reflect.Value, error) {
+	field, err := structField(v, seg)
+	if err == nil && !field.CanSet() {
+		err = fmt.Errorf("cannot set unexported field %q of %s", seg.key, v.Type())
+	}
+	return field, err
+}
+
+// pathOp is applied by updatePath to the container holding the last
+// segment of a path. It may replace the container's value in place.
+type pathOp func(container reflect.Value, seg pathSegment) error
+
+// updatePath walks v along segs and applies op to the container of the
+// final segment. Nil pointers and maps along the way are allocated, an
+// index equal to a slice's length appends a zero element, and
+// non-addressable map and interface elements are copied and written back.
+func updatePath(v reflect.Value, segs []pathSegment, op pathOp) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			if !v.CanSet() {
+				return fmt.Errorf("cannot allocate nil %s at %s", v.Type(), segs[0])
+			}
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return updatePath(v.Elem(), segs, op)
+	case reflect.Interface:
+		if v.IsNil() {
+			return fmt.Errorf("cannot walk into nil %s at %s", v.Type(), segs[0])
+		}
+		elem := reflect.New(v.Elem().Type()).Elem()
+		elem.Set(v.Elem())
+		if err := updatePath(elem, segs, op); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+	if len(segs) == 1 {
+		return op(v, segs[0])
+	}
+	seg := segs[0]
+	switch v.Kind() {
+	case reflect.Struct:
+		field, err := structField(v, seg)
+		if err != nil {
+			return err
+		}
+		return updatePath(field, segs[1:], op)
+	case reflect.Slice:
+		// An index equal to the length appends a zero element to walk into.
+		i, err := sliceIndex(seg, v.Len(), true)
+		if err != nil {
+			return err
+		}
+		if i < v.Len() {
+			return updatePath(v.Index(i), segs[1:], op)
+		}
+		old := v.Slice(0, v.Len())
+		v.Set(reflect.Append(v, reflect.Zero(v.Type().Elem())))
+		if err := updatePath(v.Index(i), segs[1:], op); err != nil {
+			v.Set(old)
+			return err
+		}
+		return nil
+	case reflect.Array:
+		i, err := sliceIndex(seg, v.Len(), false)
+		if err != nil {
+			return err
+		}
+		return updatePath(v.Index(i), segs[1:], op)
+	case reflect.Map:
+		key, err := mapKey(seg, v.Type())
+		if err != nil {
+			return err
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if existing := v.MapIndex(key); existing.IsValid() {
+			elem.Set(existing)
+		}
+		if err := updatePath(elem, segs[1:], op); err != nil {
+			return err
+		}
+		v.SetMapIndex(key, elem)
+		return nil
+	}
+	return fmt.Errorf("cannot walk into %s at %s", v.Type(), seg)
+}
+
+// setOp stores x at the final segment of a path. With insert, a slice
+// element is inserted before the index rather than replaced; either way an
+// index equal to the slice length appends.
+func setOp(x interface{}, insert bool) pathOp {
+	return func(container reflect.Value, seg pathSegment) error {
+		switch container.Kind() {
+		case reflect.Struct:
+			field, err := settableField(container, seg)
+			if err != nil {
+				return err
+			}
+			return assignValue(field, x)
+		case reflect.Slice:
+			i, err := sliceIndex(seg, container.Len(), true)
+			if err != nil {
+				return err
+			}
+			if i < container.Len() && !insert {
+				return assignValue(container.Index(i), x)
+			}
+			elem := reflect.New(container.Type().Elem()).Elem()
+			if err := assignValue(elem, x); err != nil {
+				return err
+			}
+			grown := reflect.Append(container, elem)
+			reflect.Copy(grown.Slice(i+1, grown.Len()), grown.Slice(i, grown.Len()-1))
+			grown.Index(i).Set(elem)
+			container.Set(grown)
+			return nil
+		case reflect.Array:
+			i, err := sliceIndex(seg, container.Len(), false)
+			if err != nil {
+				return err
+			}
+			return assignValue(container.Index(i), x)
+		case reflect.Map:
+			key, err := mapKey(seg, container.Type())
+			if err != nil {
+				return err
+			}
+			elem := reflect.New(container.Type().Elem()).Elem()
+			if err := assignValue(elem, x); err != nil {
+				return err
+			}
+			if container.IsNil() {
+				container.Set(reflect.MakeMap(container.Type()))
+			}
+			container.SetMapIndex(key, elem)
+			return nil
+		}
+		return fmt.Errorf("cannot set %s in %s", seg, container.Type())
+	}
+}
+
+// removeOp clears a struct field or array element, deletes a map key or
+// removes a slice element at the final segment of a path.
+func removeOp(container reflect.Value, seg pathSegment) error {
+	switch container.Kind() {
+	case reflect.Struct:
+		field, err := settableField(container, seg)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	case reflect.Slice:
+		i, err := sliceIndex(seg, container.Len(), false)
+		if err != nil {
+			return err
+		}
+		container.Set(reflect.AppendSlice(container.Slice(0, i), container.Slice(i+1, container.Len())))
+		return nil
+	case reflect.Array:
+		i, err := sliceIndex(seg, container.Len(), false)
+		if err != nil {
+			return err
+		}
+		container.Index(i).Set(reflect.Zero(container.Type().Elem()))
+		return nil
+	case reflect.Map:
+		key, err := mapKey(seg, container.Type())
+		if err != nil {
+			return err
+		}
+		if !container.IsNil() {
+			container.SetMapIndex(key, reflect.Value{})
+		}
+		return nil
+	}
+	return fmt.Errorf("cannot remove %s from %s", seg, container.Type())
+}
+
+// assignValue stores x in v, converting it when the types differ: strings
+// are parsed as by SetDefaults, numbers are converted when no precision is
+// lost, and other values fall back to a JSON round trip, so that decoded
+// JSON such as []interface{} or map[string]interface{} can be assigned to
+// slices and structs. A nil x stores the zero value.
+func assignValue(v reflect.Value, x interface{}) error {
+	if x == nil {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	xv := reflect.ValueOf(x)
+	if xv.Type().AssignableTo(v.Type()) {
+		v.Set(xv)
+		return nil
+	}
+	if v.Kind() == reflect.Ptr {
+		elem := reflect.New(v.Type().Elem())
+		if err := assignValue(elem.Elem(), x); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+	if xv.Kind() == reflect.String && v.Kind() != reflect.String {
+		return setFromString(v, xv.String())
+	}
+	if isNumberKind(xv.Kind()) && isNumberKind(v.Kind()) {
+		return convertNumber(v, xv)
+	}
+	if (v.Kind() != reflect.String || xv.Kind() == reflect.String) && xv.Type().ConvertibleTo(v.Type()) {
+		v.Set(xv.Convert(v.Type()))
+		return nil
+	}
+	data, err := json.Marshal(x)
+	if err != nil {
+		return fmt.Errorf("cannot assign %T to %s", x, v.Type())
+	}
+	target := reflect.New(v.Type())
+	if err := json.Unmarshal(data, target.Interface()); err != nil {
+		return fmt.Errorf("cannot assign %T to %s", x, v.Type())
+	}
+	v.Set(target.Elem())
+	return nil
+}
+
+func isNumberKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// convertNumber stores the number x in v, failing if the value does not fit.
+func convertNumber(v, x reflect.Value) error {
+	var f float64
+	switch x.Kind() {
+	case reflect.Float32, reflect.Float64:
+		f = x.Float()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f = float64(x.Int())
+	default:
+		f = float64(x.Uint())
+	}
+	lossy := false
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		lossy = f != math.Trunc(f) || v.OverflowInt(int64(f))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		lossy = f != math.Trunc(f) || f < 0 || v.OverflowUint(uint64(f))
+	default:
+		lossy = v.OverflowFloat(f)
+	}
+	if lossy {
+		return fmt.Errorf("%v does not fit in %s", x.Interface(), v.Type())
+	}
+	v.Set(x.Convert(v.Type()))
+	return nil
+}
+
+// Get returns the value found at path in v, e.g.
+// Get(cfg, "Database.Replicas[1].Host"). Struct fields are addressed by
+// their Go names, slice and array elements by [index], and map entries by
+// [key] or .key; quote keys containing path characters: Labels["a.b"].
+func Get(v interface{}, path string) (interface{}, error) {
+	segs, err := parsePath(path)
+	if err != nil {
+		return nil, fmt.Errorf("structutil: %w", err)
+	}
+	value := reflect.ValueOf(v)
+	for i, seg := range segs {
+		for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+			if value.IsNil() {
+				return nil, fmt.Errorf("structutil: nil value at %s", joinSegments(segs[:i]))
+			}
+			value = value.Elem()
+		}
+		switch value.Kind() {
+		case reflect.Struct:
+			value, err = structField(value, seg)
+		case reflect.Slice, reflect.Array:
+			var index int
+			if index, err = sliceIndex(seg, value.Len(), false); err == nil {
+				value = value.Index(index)
+			}
+		case reflect.Map:
+			var key reflect.Value
+			if key, err = mapKey(seg, value.Type()); err == nil {
+				if value = value.MapIndex(key); !value.IsValid() {
+					err = fmt.Errorf("no key %q", seg.key)
+				}
+			}
+		default:
+			err = fmt.Errorf("cannot walk into %s", value.Type())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("structutil: get %s: %w", path, err)
+		}
+	}
+	return interfaceOf(value), nil
+}
+
+// Set stores value at path in the value pointed to by ptr, using the path
+// syntax of Get. Nil pointers and maps along the path are allocated, an
+// index equal to a slice's length appends, and value is converted to the
+// target type when needed, e.g. the string "8080" to an int.
+func Set(ptr interface{}, path string, value interface{}) error {
+	root := reflect.ValueOf(ptr)
+	if root.Kind() != reflect.Ptr || root.IsNil() {
+		return fmt.Errorf("structutil: Set expects a non-nil pointer, got %T", ptr)
+	}
+	segs, err := parsePath(path)
+	if err != nil {
+		return fmt.Errorf("structutil: %w", err)
+	}
+	if err := updatePath(root.Elem(), segs, setOp(value, false)); err != nil {
+		return fmt.Errorf("structutil: set %s: %w", path, err)
+	}
+	return nil
+}
+
+// Fields returns the paths of all leaves of v in declaration order, with
+// map keys sorted. Leaves are scalars, structs without exported fields such
+// as time.Time, nil pointers, empty slices and maps, and pointers and maps
+// referring to a value containing them.
+func Fields(v interface{}) []string {
+	var paths []string
+	collectFields(reflect.ValueOf(v), "", &paths, make(map[pointerRef]bool))
+	return paths
+}
+
+// collectFields appends the paths of the leaves of v; active holds the
+// pointers and maps being walked, so cycles end at a leaf.
+func collectFields(v reflect.Value, path string, paths *[]string, active map[pointerRef]bool) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if ref := (pointerRef{v.Pointer(), v.Type()}); !v.IsNil() && !active[ref] {
+			active[ref] = true
+			defer delete(active, ref)
+			collectFields(v.Elem(), path, paths, active)
+			return
+		}
+	case reflect.Interface:
+		if !v.IsNil() {
+			collectFields(v.Elem(), path, paths, active)
+			return
+		}
+	case reflect.Struct:
+		info := cachedType(v.Type())
+		if !info.leaf {
+			for _, field := range info.fields {
+				collectFields(v.Field(field.index[0]), fieldPath(path, field.name), paths, active)
+			}
+			return
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Len() > 0 {
+			for i := 0; i < v.Len(); i++ {
+				collectFields(v.Index(i), indexPath(path, i), paths, active)
+			}
+			return
+		}
+	case reflect.Map:
+		if ref := (pointerRef{v.Pointer(), v.Type()}); v.Len() > 0 && !active[ref] {
+			active[ref] = true
+			defer delete(active, ref)
+			keys := v.MapKeys()
+			sort.Slice(keys, func(i, j int) bool {
+				return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+			})
+			for _, key := range keys {
+				collectFields(v.MapIndex(key), keyPath(path, key), paths, active)
+			}
+			return
+		}
+	case reflect.Invalid:
+		return
+	}
+	if path != "" {
+		*paths = append(*paths, path)
+	}
+}
+
+func joinSegments(segs []pathSegment) string {
+	var b strings.Builder
+	for i, seg := range segs {
+		if i > 0 && !seg.bracket {
+			b.WriteByte('.')
+		}
+		b.WriteString(seg.String())
+	}
+	return b.String()
+}
@@ -0,0 +1,138 @@
+package structutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type pathDatabase struct {
+	Replicas []diffReplica
+	Timeout  time.Duration
+	Primary  *diffReplica
+}
+
+type pathConfig struct {
+	Name     string
+	Database *pathDatabase
+	Labels   map[string]string
+	Weights  map[string]float64
+	Ports    [2]uint16
+	Updated  time.Time
+}
+
+func TestGet(t *testing.T) {
+	cfg := pathConfig{
+		Name:     "app",
+		Database: &pathDatabase{Replicas: []diffReplica{{Host: "db1"}, {Host: "db2", Port: 5432}}},
+		Labels:   map[string]string{"app.kubernetes.io/name": "web"},
+	}
+	v, err := Get(cfg, "Database.Replicas[1].Host")
+	assert.NoError(t, err)
+	assert.Equal(t, "db2", v)
+	v, err = Get(&cfg, "Database.Replicas[1]")
+	assert.NoError(t, err)
+	assert.Equal(t, diffReplica{Host: "db2", Port: 5432}, v)
+	v, err = Get(cfg, `Labels["app.kubernetes.io/name"]`)
+	assert.NoError(t, err)
+	assert.Equal(t, "web", v)
+
+	for _, bad := range []string{"Missing", "Database.Replicas[2]", "Database.Primary.Host", "Labels.env", "Name.Len", "A..B"} {
+		_, err := Get(cfg, bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestSet(t *testing.T) {
+	var cfg pathConfig
+	assert.NoError(t, Set(&cfg, "Database.Replicas[0].Host", "db1"))
+	assert.NoError(t, Set(&cfg, "Database.Replicas[0].Port", "5432"))
+	assert.NoError(t, Set(&cfg, "Database.Replicas[1]", map[string]interface{}{"host": "db2", "port": 5433.0}))
+	assert.NoError(t, Set(&cfg, "Database.Timeout", "5s"))
+	assert.NoError(t, Set(&cfg, "Database.Primary.Port", 5432.0))
+	assert.NoError(t, Set(&cfg, "Labels.env", "prod"))
+	assert.NoError(t, Set(&cfg, "Weights[a]", 1))
+	assert.NoError(t, Set(&cfg, "Ports[1]", 8080))
+	assert.NoError(t, Set(&cfg, "Updated", "2024-01-01T00:00:00Z"))
+	assert.Equal(t, pathConfig{
+		Database: &pathDatabase{
+			Replicas: []diffReplica{{Host: "db1", Port: 5432}, {Host: "db2", Port: 5433}},
+			Timeout:  5 * time.Second,
+			Primary:  &diffReplica{Port: 5432},
+		},
+		Labels:  map[string]string{"env": "prod"},
+		Weights: map[string]float64{"a": 1},
+		Ports:   [2]uint16{0, 8080},
+		Updated: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}, cfg)
+
+	assert.NoError(t, Set(&cfg, "Database.Replicas", []interface{}{map[string]interface{}{"host": "db9"}}))
+	assert.Equal(t, []diffReplica{{Host: "db9"}}, cfg.Database.Replicas)
+	assert.NoError(t, Set(&cfg, "Database.Primary", nil))
+	assert.Nil(t, cfg.Database.Primary)
+
+	assert.Error(t, Set(&cfg, "Ports[0]", 70000))
+	assert.Error(t, Set(&cfg, "Ports[0]", 1.5))
+	assert.Error(t, Set(&cfg, "Ports[2]", 1))
+	assert.Error(t, Set(&cfg, "Name", 1))
+	assert.Error(t, Set(&cfg, "Database.Replicas[3].Host", "x"))
+	assert.Error(t, Set(&cfg, "Database.Timeout", "soon"))
+	assert.Error(t, Set(cfg, "Name", "x"))
+}
+
+func TestFields(t *testing.T) {
+	cfg := pathConfig{
+		Name:     "app",
+		Database: &pathDatabase{Replicas: []diffReplica{{Host: "db1"}}},
+		Labels:   map[string]string{"team": "ops", "app.kubernetes.io/name": "web"},
+	}
+	assert.Equal(t, []string{
+		"Name",
+		"Database.Replicas[0].Host",
+		"Database.Replicas[0].Port",
+		"Database.Timeout",
+		"Database.Primary",
+		`Labels["app.kubernetes.io/name"]`,
+		"Labels[team]",
+		"Weights",
+		"Ports[0]",
+		"Ports[1]",
+		"Updated",
+	}, Fields(cfg))
+
+	for _, path := range Fields(&cfg) {
+		_, err := Get(cfg, path)
+		assert.NoError(t, err, path)
+	}
+}
+
+type pathNode struct {
+	Name string
+	Next *pathNode
+}
+
+type pathInner struct {
+	Host string
+}
+
+type pathOuter struct {
+	pathInner
+	Port int
+}
+
+func TestPathCyclesAndEmbedded(t *testing.T) {
+	n := pathNode{Name: "a"}
+	n.Next = &n
+	assert.Equal(t, []string{"Name", "Next.Name", "Next.Next"}, Fields(n))
+	m := map[string]interface{}{"k": 1}
+	m["self"] = m
+	assert.Equal(t, []string{"[k]", "[self]"}, Fields(m))
+
+	var outer pathOuter
+	assert.Equal(t, []string{"pathInner.Host", "Port"}, Fields(outer))
+	assert.NoError(t, Set(&outer, "pathInner.Host", "db"))
+	assert.Equal(t, "db", outer.Host)
+	assert.EqualError(t, Set(&outer, "pathInner", pathInner{}),
+		`structutil: set pathInner: cannot set unexported field "pathInner" of structutil.pathOuter`)
+}
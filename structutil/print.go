@@ -0,0 +1,262 @@
+package structutil
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// PrintTagName is the struct tag key read by Print: `print:"Header"`
+// renames a field, `print:"-"` hides it and `print:",omitempty"` hides it
+// when empty.
+const PrintTagName = "print"
+
+// PrintFormat selects how Print renders a value.
+type PrintFormat string
+
+const (
+	PrintTable PrintFormat = "table" // aligned columns, one row per struct
+	PrintTree  PrintFormat = "tree"  // nested fields drawn as a tree
+	PrintKV    PrintFormat = "kv"    // one key=value line per leaf field
+)
+
+// Print renders a struct, a slice of structs or a map to w for CLI output.
+// Tables have a column per leaf field, with nested fields named like
+// "DB.Host"; slices of scalars are shown as comma separated lists.
+func Print(w io.Writer, v interface{}, format PrintFormat) error {
+	root := newPrintNode(reflect.ValueOf(v), "", map[pointerRef]bool{})
+	switch format {
+	case PrintTable:
+		rows := []*printNode{root}
+		if kind := indirectValue(reflect.ValueOf(v)).Kind(); kind == reflect.Slice || kind == reflect.Array {
+			rows = root.children
+		}
+		return printTable(w, rows)
+	case PrintTree:
+		return printTree(w, root, typeName(v))
+	case PrintKV:
+		return printKV(w, root)
+	}
+	return fmt.Errorf("structutil: unknown print format %q", format)
+}
+
+// printNode is a value prepared for printing: a leaf with its formatted
+// value, or a struct, slice or map with named children.
+type printNode struct {
+	name     string
+	value    string
+	isNil    bool
+	leaf     bool
+	children []*printNode
+}
+
+// pointerRef identifies the value a pointer refers to; the type tells apart
+// a struct and its first field, which share an address.
+type pointerRef struct {
+	addr uintptr
+	typ  reflect.Type
+}
+
+// newPrintNode prepares v for printing. Pointers to values being printed
+// above v, which would recurse forever, are printed as leaves.
+func newPrintNode(v reflect.Value, name string, active map[pointerRef]bool) *printNode {
+	n := &printNode{name: name}
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			n.leaf, n.isNil = true, true
+			return n
+		}
+		if v.Kind() == reflect.Ptr {
+			ref := pointerRef{v.Pointer(), v.Type()}
+			if active[ref] {
+				n.leaf, n.value = true, fmt.Sprint(interfaceOf(v))
+				return n
+			}
+			active[ref] = true
+			defer delete(active, ref)
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		n.leaf, n.isNil = true, true
+		return n
+	}
+	switch {
+	case v.Kind() == reflect.Map && !isEnvScalar(v.Type().Elem()):
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, key := range keys {
+			n.children = append(n.children, newPrintNode(v.MapIndex(key), fmt.Sprintf("[%v]", key.Interface()), active))
+		}
+		return n
+	case isEnvScalar(v.Type()):
+		n.leaf = true
+		if s, err := formatEnv(v); err == nil {
+			n.value = s
+		} else {
+			n.value = fmt.Sprint(interfaceOf(v))
+		}
+		return n
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		for _, field := range cachedType(v.Type()).fields {
+			tag, _ := field.tags.Get(PrintTagName)
+			if tag != nil && tag.Name == "-" && len(tag.Options) == 0 {
+				continue
+			}
+			fv := v.FieldByIndex(field.index)
+			if tag != nil && tag.HasOption("omitempty") && isEmpty(fv, defaultEmptyOptions) {
+				continue
+			}
+			name := field.name
+			if tag != nil && tag.Name != "" {
+				name = tag.Name
+			}
+			child := newPrintNode(fv, name, active)
+			switch {
+			case field.anonymous && (tag == nil || tag.Name == "") && !child.leaf:
+				// Fields of embedded structs are shown as if declared here.
+				n.children = append(n.children, child.children...)
+			case field.exported:
+				n.children = append(n.children, child)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			n.children = append(n.children, newPrintNode(v.Index(i), "["+strconv.Itoa(i)+"]", active))
+		}
+	}
+	return n
+}
+
+// flatten appends the leaves below n as (path, value) pairs.
+func (n *printNode) flatten(path string, leaves *[][2]string) {
+	if n.leaf {
+		*leaves = append(*leaves, [2]string{path, n.value})
+		return
+	}
+	for _, child := range n.children {
+		childPath := child.name
+		if path != "" && strings.HasPrefix(child.name, "[") {
+			childPath = path + child.name
+		} else if path != "" {
+			childPath = path + "." + child.name
+		}
+		child.flatten(childPath, leaves)
+	}
+}
+
+func printTable(w io.Writer, rows []*printNode) error {
+	var columns []string
+	index := make(map[string]int)
+	var cells []map[string]string
+	for _, row := range rows {
+		var leaves [][2]string
+		row.flatten("", &leaves)
+		values := make(map[string]string, len(leaves))
+		for _, leaf := range leaves {
+			if _, ok := index[leaf[0]]; !ok {
+				index[leaf[0]] = len(columns)
+				columns = append(columns, leaf[0])
+			}
+			values[leaf[0]] = leaf[1]
+		}
+		cells = append(cells, values)
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+	var buf strings.Builder
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(columns, "\t"))
+	for _, values := range cells {
+		line := make([]string, len(columns))
+		for i, column := range columns {
+			line[i] = values[column]
+		}
+		fmt.Fprintln(tw, strings.Join(line, "\t"))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	// Empty trailing cells leave padding behind.
+	lines := strings.SplitAfter(buf.String(), "\n")
+	for i, line := range lines {
+		if strings.HasSuffix(line, "\n") {
+			lines[i] = strings.TrimRight(line, " \n") + "\n"
+		}
+	}
+	_, err := io.WriteString(w, strings.Join(lines, ""))
+	return err
+}
+
+func printTree(w io.Writer, root *printNode, title string) error {
+	var b strings.Builder
+	line := title
+	if root.leaf {
+		line += ": " + root.display()
+	}
+	b.WriteString(strings.TrimRight(line, " ") + "\n")
+	writeTreeChildren(&b, root, "")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeTreeChildren(b *strings.Builder, n *printNode, indent string) {
+	for i, child := range n.children {
+		branch, next := "├── ", "│   "
+		if i == len(n.children)-1 {
+			branch, next = "└── ", "    "
+		}
+		line := indent + branch + child.name
+		if child.leaf {
+			line += ": " + child.display()
+		}
+		// Empty values and names leave no padding behind.
+		b.WriteString(strings.TrimRight(line, " ") + "\n")
+		writeTreeChildren(b, child, indent+next)
+	}
+}
+
+func (n *printNode) display() string {
+	if n.isNil {
+		return "<nil>"
+	}
+	return n.value
+}
+
+func printKV(w io.Writer, root *printNode) error {
+	var leaves [][2]string
+	root.flatten("", &leaves)
+	var b strings.Builder
+	for _, leaf := range leaves {
+		value := leaf[1]
+		if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+			value = strconv.Quote(value)
+		}
+		b.WriteString(leaf[0] + "=" + value + "\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func typeName(v interface{}) string {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return "<nil>"
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Name() != "" {
+		return t.Name()
+	}
+	return t.String()
+}
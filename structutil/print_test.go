@@ -0,0 +1,105 @@
+package structutil
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type printServer struct {
+	Name    string        `print:"NAME"`
+	Addr    printAddr     `print:"ADDR"`
+	Tags    []string      `print:"TAGS"`
+	Timeout time.Duration `print:"TIMEOUT,omitempty"`
+	Owner   *string
+	Secret  string `print:"-"`
+}
+
+type printAddr struct {
+	Host string
+	Port int
+}
+
+func printFixtures() []printServer {
+	owner := "ops team"
+	return []printServer{
+		{Name: "web", Addr: printAddr{"10.0.0.1", 80}, Tags: []string{"a", "b"}, Timeout: time.Second, Owner: &owner, Secret: "x"},
+		{Name: "db", Addr: printAddr{"10.0.0.2", 5432}},
+	}
+}
+
+func TestPrintTable(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, Print(&buf, printFixtures(), PrintTable))
+	assert.Equal(t, `NAME  ADDR.Host  ADDR.Port  TAGS  TIMEOUT  Owner
+web   10.0.0.1   80         a,b   1s       ops team
+db    10.0.0.2   5432
+`, buf.String())
+
+	buf.Reset()
+	assert.NoError(t, Print(&buf, &printFixtures()[1], PrintTable))
+	assert.Equal(t, `NAME  ADDR.Host  ADDR.Port  TAGS  Owner
+db    10.0.0.2   5432
+`, buf.String())
+}
+
+func TestPrintTree(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, Print(&buf, printFixtures(), PrintTree))
+	assert.Equal(t, `[]structutil.printServer
+├── [0]
+│   ├── NAME: web
+│   ├── ADDR
+│   │   ├── Host: 10.0.0.1
+│   │   └── Port: 80
+│   ├── TAGS: a,b
+│   ├── TIMEOUT: 1s
+│   └── Owner: ops team
+└── [1]
+    ├── NAME: db
+    ├── ADDR
+    │   ├── Host: 10.0.0.2
+    │   └── Port: 5432
+    ├── TAGS:
+    └── Owner: <nil>
+`, buf.String())
+}
+
+type printList struct {
+	Value int
+	Next  *printList
+}
+
+func TestPrintTreeRecursive(t *testing.T) {
+	// Nested values of the same type are printed in full.
+	list := &printList{1, &printList{2, &printList{3, nil}}}
+	var buf bytes.Buffer
+	assert.NoError(t, Print(&buf, list, PrintKV))
+	assert.Equal(t, "Value=1\nNext.Value=2\nNext.Next.Value=3\nNext.Next.Next=\"\"\n", buf.String())
+
+	// Cycles are cut where a value refers to one being printed.
+	list.Next.Next.Next = list
+	buf.Reset()
+	assert.NoError(t, Print(&buf, list, PrintTree))
+	assert.Contains(t, buf.String(), "        ├── Value: 3\n        └── Next: &{1 ")
+}
+
+func TestPrintKV(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, Print(&buf, printFixtures()[0], PrintKV))
+	assert.Equal(t, `NAME=web
+ADDR.Host=10.0.0.1
+ADDR.Port=80
+TAGS=a,b
+TIMEOUT=1s
+Owner="ops team"
+`, buf.String())
+
+	buf.Reset()
+	assert.NoError(t, Print(&buf, map[string]printAddr{"b": {"h", 1}, "a": {"g", 2}}, PrintKV))
+	assert.Equal(t, "[a].Host=g\n[a].Port=2\n[b].Host=h\n[b].Port=1\n", buf.String())
+
+	assert.Error(t, Print(&buf, printFixtures(), "xml"))
+}
@@ -0,0 +1,112 @@
+package structutil
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// SensitiveTagName is the struct tag key marking fields Redact must mask.
+// `sensitive:"true"` masks a field whatever its name, `sensitive:"false"`
+// exempts it from name matching.
+const SensitiveTagName = "sensitive"
+
+// RedactMask replaces the strings masked by Redact.
+const RedactMask = "******"
+
+// DefaultSensitiveNames are the case-insensitive field name fragments
+// Redact masks by default.
+var DefaultSensitiveNames = []string{"password", "secret", "token"}
+
+// RedactOption customises how Redact finds and masks sensitive fields.
+type RedactOption func(o *redactOptions)
+
+type redactOptions struct {
+	names []string
+	mask  string
+}
+
+// WithSensitiveNames replaces the field name fragments matched by Redact.
+func WithSensitiveNames(names ...string) RedactOption {
+	return func(o *redactOptions) {
+		o.names = make([]string, len(names))
+		for i, name := range names {
+			o.names[i] = strings.ToLower(name)
+		}
+	}
+}
+
+// WithRedactMask replaces the string masked values are replaced with.
+func WithRedactMask(mask string) RedactOption {
+	return func(o *redactOptions) { o.mask = mask }
+}
+
+// How much of a value the cloner masks when redacting.
+const (
+	maskNone    = iota
+	maskStrings // name matches: mask strings, keep numbers and flags readable
+	maskAll     // tagged sensitive: mask strings and zero everything else
+)
+
+// Redact returns a deep copy of v in which sensitive values are masked, so
+// that configs can be logged or dumped safely. A field is sensitive if it is
+// tagged `sensitive:"true"` or its name contains one of the sensitive names
+// (password, secret, token by default); map entries are matched by key.
+// Non-empty strings inside sensitive values are replaced with RedactMask,
+// and other values of tagged fields are zeroed. Redact returns a value of
+// the same type as v, so a *Config yields a new *Config. Unexported fields
+// are copied as they are.
+func Redact(v interface{}, opts ...RedactOption) interface{} {
+	if v == nil {
+		return nil
+	}
+	o := &redactOptions{names: DefaultSensitiveNames, mask: RedactMask}
+	for _, opt := range opts {
+		opt(o)
+	}
+	c := &cloner{seen: make(map[cloneKey]reflect.Value), redact: o}
+	return c.clone(reflect.ValueOf(v), maskNone).Interface()
+}
+
+// RedactMap is StructToMap applied to the redacted copy of v.
+func RedactMap(v interface{}, opts ...RedactOption) map[string]interface{} {
+	return StructToMap(Redact(v, opts...))
+}
+
+// Redacted wraps v in a zapcore.ObjectMarshaler that logs the redacted
+// copy of v, e.g. logger.Info("starting", zap.Object("config", Redacted(cfg))).
+func Redacted(v interface{}, opts ...RedactOption) zapcore.ObjectMarshaler {
+	return redactedObject{v: v, opts: opts}
+}
+
+type redactedObject struct {
+	v    interface{}
+	opts []RedactOption
+}
+
+func (r redactedObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	m := RedactMap(r.v, r.opts...)
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := enc.AddReflected(k, m[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *redactOptions) sensitiveName(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range o.names {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
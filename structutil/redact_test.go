@@ -0,0 +1,88 @@
+package structutil
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type redactDatabase struct {
+	Host     string `json:"host"`
+	Password string `json:"password"`
+	PIN      int    `json:"pin" sensitive:"true"`
+}
+
+type redactConfig struct {
+	Name        string            `json:"name"`
+	APIToken    string            `json:"api_token"`
+	TokenTTL    int               `json:"token_ttl"`
+	Database    *redactDatabase   `json:"database"`
+	Replicas    []redactDatabase  `json:"replicas"`
+	Headers     map[string]string `json:"headers"`
+	Credentials []string          `json:"credentials" sensitive:"true"`
+	SecretHint  string            `json:"secret_hint" sensitive:"false"`
+	Key         []byte            `json:"key" sensitive:"true"`
+	Next        *redactConfig     `json:"-"`
+	secret      string
+}
+
+func redactFixture() *redactConfig {
+	return &redactConfig{
+		Name:        "app",
+		APIToken:    "t0k3n",
+		TokenTTL:    60,
+		Database:    &redactDatabase{Host: "db", Password: "pw", PIN: 1234},
+		Replicas:    []redactDatabase{{Host: "db1", Password: ""}},
+		Headers:     map[string]string{"Accept": "json", "X-Auth-Token": "abc"},
+		Credentials: []string{"a", "b"},
+		SecretHint:  "ask ops",
+		Key:         []byte("key"),
+		secret:      "kept",
+	}
+}
+
+func TestRedact(t *testing.T) {
+	cfg := redactFixture()
+	cfg.Next = cfg
+	redacted := Redact(cfg).(*redactConfig)
+
+	assert.Equal(t, "app", redacted.Name)
+	assert.Equal(t, RedactMask, redacted.APIToken)
+	assert.Equal(t, 60, redacted.TokenTTL)
+	assert.Equal(t, &redactDatabase{Host: "db", Password: RedactMask, PIN: 0}, redacted.Database)
+	assert.Equal(t, []redactDatabase{{Host: "db1"}}, redacted.Replicas)
+	assert.Equal(t, map[string]string{"Accept": "json", "X-Auth-Token": RedactMask}, redacted.Headers)
+	assert.Equal(t, []string{RedactMask, RedactMask}, redacted.Credentials)
+	assert.Equal(t, "ask ops", redacted.SecretHint)
+	assert.Equal(t, []byte(RedactMask), redacted.Key)
+	assert.Equal(t, "kept", redacted.secret)
+	assert.Same(t, redacted, redacted.Next)
+
+	// The original is untouched.
+	assert.Equal(t, "pw", cfg.Database.Password)
+	assert.Equal(t, "abc", cfg.Headers["X-Auth-Token"])
+	assert.Equal(t, []string{"a", "b"}, cfg.Credentials)
+
+	value := Redact(*cfg, WithSensitiveNames("Host"), WithRedactMask("x")).(redactConfig)
+	assert.Equal(t, "x", value.Database.Host)
+	assert.Equal(t, "pw", value.Database.Password)
+	assert.Nil(t, Redact(nil))
+}
+
+func TestRedactMap(t *testing.T) {
+	m := RedactMap(redactFixture())
+	assert.Equal(t, RedactMask, m["api_token"])
+	assert.Equal(t, map[string]interface{}{"host": "db", "password": RedactMask, "pin": float64(0)}, m["database"])
+}
+
+func TestRedacted(t *testing.T) {
+	var buf bytes.Buffer
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zapcore.EncoderConfig{MessageKey: "msg"}), zapcore.AddSync(&buf), zap.InfoLevel)
+	zap.New(core).Info("start", zap.Object("config", Redacted(redactFixture())))
+	assert.Contains(t, buf.String(), `"api_token":"******"`)
+	assert.Contains(t, buf.String(), `"database":{"host":"db","password":"******","pin":0}`)
+	assert.NotContains(t, buf.String(), "t0k3n")
+}
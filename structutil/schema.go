@@ -0,0 +1,417 @@
+package structutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/reggiepy/goutils/v2/enumutil"
+)
+
+// DescriptionTagName is the struct tag key JSONSchema reads field descriptions from.
+const DescriptionTagName = "description"
+
+// SchemaDraft is the JSON Schema dialect produced by JSONSchema.
+const SchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema document or subschema.
+type Schema struct {
+	Schema      string        `json:"$schema,omitempty"`
+	Ref         string        `json:"$ref,omitempty"`
+	Title       string        `json:"title,omitempty"`
+	Description string        `json:"description,omitempty"`
+	Type        string        `json:"type,omitempty"`
+	Format      string        `json:"format,omitempty"`
+	Enum        []interface{} `json:"enum,omitempty"`
+	Default     interface{}   `json:"default,omitempty"`
+
+	Minimum   *float64 `json:"minimum,omitempty"`
+	Maximum   *float64 `json:"maximum,omitempty"`
+	MinLength *int     `json:"minLength,omitempty"`
+	MaxLength *int     `json:"maxLength,omitempty"`
+	Pattern   string   `json:"pattern,omitempty"`
+
+	Items    *Schema `json:"items,omitempty"`
+	MinItems *int    `json:"minItems,omitempty"`
+	MaxItems *int    `json:"maxItems,omitempty"`
+
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"` // false or a *Schema
+	MinProperties        *int               `json:"minProperties,omitempty"`
+	MaxProperties        *int               `json:"maxProperties,omitempty"`
+
+	AnyOf           []*Schema          `json:"anyOf,omitempty"`
+	ContentEncoding string             `json:"contentEncoding,omitempty"`
+	Defs            map[string]*Schema `json:"$defs,omitempty"`
+}
+
+// SchemaOption customises JSONSchema.
+type SchemaOption func(o *schemaOptions)
+
+type schemaOptions struct {
+	tagName string
+}
+
+// WithSchemaTagName names properties after the given tag key, e.g. "yaml",
+// instead of json tags with yaml tags as a fallback. Untagged fields are
+// named the way the matching encoder names them.
+func WithSchemaTagName(key string) SchemaOption {
+	return func(o *schemaOptions) { o.tagName = key }
+}
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	enumType = reflect.TypeOf(enumutil.Enum{})
+)
+
+// JSONSchema describes the type of v as a JSON Schema (draft 2020-12), so
+// that editors can validate and complete configuration files. Properties are
+// named after json tags, falling back to yaml tags, and annotated from the
+// `description`, `default` and `validate` tags: required fields are listed
+// as required, min/max/len become bounds, oneof becomes an enum, email and
+// url become formats and regexp a pattern. Structs do not allow unknown
+// properties. The Value of enumutil.Enum fields is restricted to the
+// allowed values found in v. Recursive types are placed in $defs.
+func JSONSchema(v interface{}, opts ...SchemaOption) (*Schema, error) {
+	o := &schemaOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	value := reflect.ValueOf(v)
+	if !value.IsValid() {
+		return nil, fmt.Errorf("structutil: JSONSchema called with nil")
+	}
+	g := &schemaGenerator{
+		options:    o,
+		active:     make(map[reflect.Type]bool),
+		referenced: make(map[reflect.Type]bool),
+		defs:       make(map[string]*Schema),
+	}
+	s, err := g.schema(value.Type(), value)
+	if err != nil {
+		return nil, fmt.Errorf("structutil: %w", err)
+	}
+	if s.Ref == "" {
+		s.Title = typeName(v)
+	}
+	s.Schema = SchemaDraft
+	if len(g.defs) > 0 {
+		s.Defs = g.defs
+	}
+	return s, nil
+}
+
+type schemaGenerator struct {
+	options    *schemaOptions
+	active     map[reflect.Type]bool // struct types being described
+	referenced map[reflect.Type]bool // struct types referred to from within themselves
+	defs       map[string]*Schema
+}
+
+// schema describes t; v is a value of type t when one is known, and is
+// only used to read enumutil.Enum values.
+func (g *schemaGenerator) schema(t reflect.Type, v reflect.Value) (*Schema, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		if v.IsValid() && !v.IsNil() {
+			v = v.Elem()
+		} else {
+			v = reflect.Value{}
+		}
+	}
+	switch {
+	case t == enumType:
+		return g.enumSchema(v)
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}, nil
+	case t == durationType:
+		return &Schema{AnyOf: []*Schema{{Type: "string"}, {Type: "integer"}}}, nil
+	case mayHaveMethods(t) && reflect.PtrTo(t).Implements(textUnmarshalerType):
+		return &Schema{Type: "string"}, nil
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: "integer"}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		zero := 0.0
+		return &Schema{Type: "integer", Minimum: &zero}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}, nil
+	case reflect.String:
+		return &Schema{Type: "string"}, nil
+	case reflect.Interface:
+		return &Schema{}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 && t.Kind() == reflect.Slice {
+			return &Schema{Type: "string", ContentEncoding: "base64"}, nil
+		}
+		items, err := g.schema(t.Elem(), reflect.Value{})
+		if err != nil {
+			return nil, err
+		}
+		s := &Schema{Type: "array", Items: items}
+		if t.Kind() == reflect.Array {
+			n := t.Len()
+			s.MinItems, s.MaxItems = &n, &n
+		}
+		return s, nil
+	case reflect.Map:
+		switch t.Key().Kind() {
+		case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		default:
+			return nil, fmt.Errorf("unsupported map key type %s", t.Key())
+		}
+		elem, err := g.schema(t.Elem(), reflect.Value{})
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "object", AdditionalProperties: elem}, nil
+	case reflect.Struct:
+		return g.structSchema(t, v)
+	}
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+// enumSchema describes an enumutil.Enum, encoded as a struct, restricting
+// its value to the allowed values of v when v is known.
+func (g *schemaGenerator) enumSchema(v reflect.Value) (*Schema, error) {
+	s, err := g.structSchema(enumType, v)
+	if err != nil || !v.IsValid() {
+		return s, err
+	}
+	e, ok := interfaceOf(v).(enumutil.Enum)
+	if !ok {
+		return s, nil
+	}
+	for _, field := range cachedType(enumType).fields {
+		if field.name != "Value" {
+			continue
+		}
+		name, _, _ := g.propertyName(field)
+		if value := s.Properties[name]; value != nil {
+			for _, allowed := range e.Allowed {
+				value.Enum = append(value.Enum, allowed)
+			}
+			if e.Value != "" {
+				value.Default = e.Value
+			}
+		}
+	}
+	return s, nil
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type, v reflect.Value) (*Schema, error) {
+	if g.active[t] {
+		g.referenced[t] = true
+		return &Schema{Ref: "#/$defs/" + defName(t)}, nil
+	}
+	g.active[t] = true
+	defer delete(g.active, t)
+
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema), AdditionalProperties: false}
+	if err := g.addProperties(s, t, v); err != nil {
+		return nil, err
+	}
+	if g.referenced[t] {
+		g.defs[defName(t)] = s
+		return &Schema{Ref: "#/$defs/" + defName(t)}, nil
+	}
+	return s, nil
+}
+
+func (g *schemaGenerator) addProperties(s *Schema, t reflect.Type, v reflect.Value) error {
+	for _, field := range cachedType(t).fields {
+		var fv reflect.Value
+		if v.IsValid() {
+			fv = v.Field(field.index[0])
+		}
+		name, inline, ok := g.propertyName(field)
+		if !ok {
+			continue
+		}
+		if inline {
+			ft, fv := field.typ, fv
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+				if fv.IsValid() && !fv.IsNil() {
+					fv = fv.Elem()
+				} else {
+					fv = reflect.Value{}
+				}
+			}
+			if err := g.addProperties(s, ft, fv); err != nil {
+				return err
+			}
+			continue
+		}
+		prop, err := g.schema(field.typ, fv)
+		if err != nil {
+			return fmt.Errorf("field %s.%s: %w", t.Name(), field.name, err)
+		}
+		if err := annotateProperty(prop, field); err != nil {
+			return fmt.Errorf("field %s.%s: %w", t.Name(), field.name, err)
+		}
+		s.Properties[name] = prop
+		if field.validate.required {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return nil
+}
+
+// propertyName returns the property name of a field, or inline for embedded
+// structs whose fields are promoted, and false for skipped fields.
+func (g *schemaGenerator) propertyName(field fieldInfo) (string, bool, bool) {
+	keys := []string{"json", "yaml"}
+	if g.options.tagName != "" {
+		keys = []string{g.options.tagName}
+	}
+	for _, key := range keys {
+		tag, ok := field.tags.Get(key)
+		if !ok {
+			continue
+		}
+		if tag.Name == "-" && len(tag.Options) == 0 {
+			return "", false, false
+		}
+		if tag.HasOption("inline") {
+			return "", true, true
+		}
+		if tag.Name != "" {
+			return tag.Name, false, field.exported
+		}
+		// A tag without a name, e.g. `json:",omitempty"`, keeps the default name.
+		break
+	}
+	if field.anonymous {
+		if t := field.typ; t.Kind() == reflect.Struct || t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct {
+			return "", true, true
+		}
+	}
+	if keys[0] == "yaml" {
+		// yaml.v3 lowercases untagged field names.
+		return strings.ToLower(field.name), false, field.exported
+	}
+	return field.name, false, field.exported
+}
+
+// annotateProperty adds the description, default and validate tags of field to s.
+func annotateProperty(s *Schema, field fieldInfo) error {
+	s.Description = field.tag(DescriptionTagName)
+	if field.hasDef {
+		def, err := defaultJSONValue(field.typ, field.defValue)
+		if err != nil {
+			return err
+		}
+		s.Default = def
+	}
+	t := field.typ
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for _, r := range field.validate.rules {
+		switch r.name {
+		case "min", "max", "len":
+			if t == durationType {
+				continue
+			}
+			n, err := strconv.ParseFloat(r.param, 64)
+			if err != nil {
+				continue
+			}
+			applyBound(s, t, r.name, n)
+		case "oneof":
+			s.Enum = nil
+			for _, option := range strings.Fields(r.param) {
+				value, err := defaultJSONValue(t, option)
+				if err != nil {
+					return err
+				}
+				s.Enum = append(s.Enum, value)
+			}
+		case "email":
+			s.Format = "email"
+		case "url":
+			s.Format = "uri"
+		case "regexp":
+			s.Pattern = r.param
+		}
+	}
+	return nil
+}
+
+// applyBound turns a min, max or len rule into the matching JSON Schema keywords.
+func applyBound(s *Schema, t reflect.Type, rule string, n float64) {
+	count := int(n)
+	switch t.Kind() {
+	case reflect.String:
+		if rule != "max" {
+			s.MinLength = &count
+		}
+		if rule != "min" {
+			s.MaxLength = &count
+		}
+	case reflect.Slice, reflect.Array:
+		if rule != "max" {
+			s.MinItems = &count
+		}
+		if rule != "min" {
+			s.MaxItems = &count
+		}
+	case reflect.Map:
+		if rule != "max" {
+			s.MinProperties = &count
+		}
+		if rule != "min" {
+			s.MaxProperties = &count
+		}
+	default:
+		if rule != "max" {
+			s.Minimum = &n
+		}
+		if rule != "min" {
+			s.Maximum = &n
+		}
+	}
+}
+
+// defaultJSONValue parses s as SetDefaults would and returns it as it
+// appears in JSON. Durations are kept as written.
+func defaultJSONValue(t reflect.Type, s string) (interface{}, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == durationType {
+		if _, err := time.ParseDuration(s); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	v := reflect.New(t).Elem()
+	if err := setFromString(v, s); err != nil {
+		return nil, fmt.Errorf("invalid value %q: %w", s, err)
+	}
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func defName(t reflect.Type) string {
+	if t.Name() != "" {
+		return t.Name()
+	}
+	return strings.NewReplacer(" ", "", "{", "", "}", "", ";", "_").Replace(t.String())
+}
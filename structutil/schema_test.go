@@ -0,0 +1,99 @@
+package structutil
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/reggiepy/goutils/v2/enumutil"
+	"github.com/stretchr/testify/assert"
+)
+
+type schemaBase struct {
+	ID string `json:"id" validate:"required"`
+}
+
+type schemaNode struct {
+	Name     string        `json:"name"`
+	Children []*schemaNode `json:"children"`
+}
+
+type schemaConfig struct {
+	schemaBase
+	Level    string          `json:"level" yaml:"log_level" default:"info" validate:"oneof=debug info" description:"log level"`
+	Port     int             `json:"port" default:"8080" validate:"min=1,max=65535"`
+	Ratio    float64         `yaml:"ratio"`
+	Email    string          `json:"email,omitempty" validate:"email"`
+	Tags     []string        `json:"tags" validate:"min=1"`
+	Labels   map[string]uint `json:"labels"`
+	Timeout  time.Duration   `json:"timeout" default:"5s"`
+	Started  time.Time       `json:"started"`
+	Mode     *enumutil.Enum  `json:"mode"`
+	Tree     *schemaNode     `json:"tree"`
+	Ignored  func()          `json:"-" yaml:"-"`
+	Untagged bool
+	Extra    map[string]string `json:",omitempty" yaml:"extra"`
+}
+
+func TestJSONSchema(t *testing.T) {
+	s, err := JSONSchema(&schemaConfig{Mode: enumutil.NewEnum([]string{"fast", "safe"}, "safe")})
+	assert.NoError(t, err)
+	data, err := json.Marshal(s)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title": "schemaConfig",
+		"type": "object",
+		"properties": {
+			"id": {"type": "string"},
+			"level": {"type": "string", "description": "log level", "default": "info", "enum": ["debug", "info"]},
+			"port": {"type": "integer", "default": 8080, "minimum": 1, "maximum": 65535},
+			"ratio": {"type": "number"},
+			"email": {"type": "string", "format": "email"},
+			"tags": {"type": "array", "items": {"type": "string"}, "minItems": 1},
+			"labels": {"type": "object", "additionalProperties": {"type": "integer", "minimum": 0}},
+			"timeout": {"anyOf": [{"type": "string"}, {"type": "integer"}], "default": "5s"},
+			"started": {"type": "string", "format": "date-time"},
+			"mode": {
+				"type": "object",
+				"properties": {
+					"Allowed": {"type": "array", "items": {"type": "string"}},
+					"Value": {"type": "string", "enum": ["fast", "safe"], "default": "safe"}
+				},
+				"additionalProperties": false
+			},
+			"tree": {"$ref": "#/$defs/schemaNode"},
+			"Untagged": {"type": "boolean"},
+			"Extra": {"type": "object", "additionalProperties": {"type": "string"}}
+		},
+		"required": ["id"],
+		"additionalProperties": false,
+		"$defs": {
+			"schemaNode": {
+				"type": "object",
+				"properties": {
+					"name": {"type": "string"},
+					"children": {"type": "array", "items": {"$ref": "#/$defs/schemaNode"}}
+				},
+				"additionalProperties": false
+			}
+		}
+	}`, string(data))
+
+	s, err = JSONSchema(schemaConfig{}, WithSchemaTagName("yaml"))
+	assert.NoError(t, err)
+	assert.Contains(t, s.Properties, "log_level")
+	assert.Contains(t, s.Properties, "extra")
+	assert.Contains(t, s.Properties, "untagged")
+	assert.Contains(t, s.Properties["mode"].Properties, "allowed")
+	assert.Equal(t, &Schema{Type: "string"}, s.Properties["mode"].Properties["value"])
+
+	_, err = JSONSchema(struct{ C chan int }{})
+	assert.Error(t, err)
+	_, err = JSONSchema(struct {
+		N int `default:"x"`
+	}{})
+	assert.Error(t, err)
+	_, err = JSONSchema(nil)
+	assert.Error(t, err)
+}
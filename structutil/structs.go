@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"sync"
 )
 
 // CopyIntersectionStruct assign values between two struct assignments(A and B) that have intersection.
@@ -11,77 +12,41 @@ import (
 func CopyIntersectionStruct(src, dst interface{}) {
 	sElement := reflect.ValueOf(src).Elem()
 	dElement := reflect.ValueOf(dst).Elem()
-	for i := 0; i < dElement.NumField(); i++ {
-		dField := dElement.Type().Field(i)
-		sValue := sElement.FieldByName(dField.Name)
-		if !sValue.IsValid() {
-			continue
-		}
-		value := dElement.Field(i)
-		value.Set(sValue)
+	for _, pair := range cachedCopyPlan(sElement.Type(), dElement.Type()) {
+		dElement.Field(pair.dst).Set(sElement.FieldByIndex(pair.src))
 	}
 }
 
-type valueIsEmptyFunc func(v reflect.Value) bool
-
-var valueIsEmptyFuncMap = map[reflect.Kind]valueIsEmptyFunc{
-	reflect.Int:        intValueIsEmpty,
-	reflect.Int8:       intValueIsEmpty,
-	reflect.Int16:      intValueIsEmpty,
-	reflect.Int32:      intValueIsEmpty,
-	reflect.Int64:      intValueIsEmpty,
-	reflect.Uint:       uintValueIsEmpty,
-	reflect.Uint8:      uintValueIsEmpty,
-	reflect.Uint16:     uintValueIsEmpty,
-	reflect.Uint32:     uintValueIsEmpty,
-	reflect.Uint64:     uintValueIsEmpty,
-	reflect.Complex64:  completeValueIsEmpty,
-	reflect.Complex128: completeValueIsEmpty,
-	reflect.Array:      sliceValueIsEmpty,
-	reflect.Slice:      sliceValueIsEmpty,
-	reflect.String:     stringValueIsEmpty,
-}
-
-func intValueIsEmpty(v reflect.Value) bool {
-	return v.Int() == 0
-}
-
-func uintValueIsEmpty(v reflect.Value) bool {
-	return v.Uint() == 0
-}
-
-func completeValueIsEmpty(v reflect.Value) bool {
-	return v.Complex() == 0
+type copyPair struct {
+	src []int
+	dst int
 }
 
-func stringValueIsEmpty(v reflect.Value) bool {
-	return len(v.String()) == 0
-}
+var copyPlanCache sync.Map // map[[2]reflect.Type][]copyPair
 
-func sliceValueIsEmpty(v reflect.Value) bool {
-	return v.IsNil() || v.Len() == 0
+// cachedCopyPlan returns, for each field of dst, the index path of the src
+// field of the same name, found the way FieldByName finds it. Copying
+// panics as reflect.Value.Set does for fields that cannot be assigned.
+func cachedCopyPlan(src, dst reflect.Type) []copyPair {
+	key := [2]reflect.Type{src, dst}
+	if cached, ok := copyPlanCache.Load(key); ok {
+		return cached.([]copyPair)
+	}
+	var plan []copyPair
+	for i := 0; i < dst.NumField(); i++ {
+		if sField, ok := src.FieldByName(dst.Field(i).Name); ok {
+			plan = append(plan, copyPair{src: sField.Index, dst: i})
+		}
+	}
+	copyPlanCache.Store(key, plan)
+	return plan
 }
 
 // IsStructEmpty
 // @Description: reflect.DeepEqual() 的方式判空的话如果slice是 初始化了长度为0 则无法判断，通过该方法slice为0仍然会认定是空
-func IsStructEmpty(v interface{}) bool {
-	vType := reflect.TypeOf(v)
-	value := reflect.ValueOf(v)
-	for i := 0; i < value.NumField(); i++ {
-		field := vType.Field(i)
-		// 结构体变量递归判断
-		if field.Type.Kind() == reflect.Struct && field.Type.NumField() > 0 {
-			if isEmpty := IsStructEmpty(value.FieldByName(field.Name).Interface()); !isEmpty {
-				return false
-			}
-		}
-		if isEmptyFunc, ok := valueIsEmptyFuncMap[field.Type.Kind()]; ok {
-			if isEmpty := isEmptyFunc(value.Field(i)); !isEmpty {
-				return false
-			}
-		}
-	}
-	return true
+// 支持传入结构体指针（nil 指针视为空），各类字段的判空规则见 IsZero
+func IsStructEmpty(v interface{}, opts ...EmptyOption) bool {
+	return isEmpty(indirectValue(reflect.ValueOf(v)), newEmptyOptions(opts))
 }
 
 func StructToMap(data interface{}) map[string]interface{} {
@@ -112,9 +77,14 @@ func IsEmptyStringField(s interface{}, fields ...string) (bool, error) {
 	}
 
 	// 遍历要检查的字段
+	info := cachedType(value.Type())
 	for _, field := range fields {
 		// 获取字段的反射值
-		fieldValue := value.FieldByName(field)
+		f, ok := info.field(field)
+		if !ok {
+			continue
+		}
+		fieldValue := value.FieldByIndex(f.index)
 
 		// 如果字段是字符串类型并且为空字符串，则返回true
 		if fieldValue.Kind() == reflect.String && fieldValue.String() == "" {
@@ -36,6 +36,29 @@ func TestCopyIntersectionStruct(t *testing.T) {
 	println(b)
 }
 
+func TestCopyIntersectionStructMismatch(t *testing.T) {
+	type src struct {
+		Name string
+		Age  int
+	}
+	type dst struct {
+		Name string
+		Age  string
+	}
+	// Fields of the same name must have assignable types.
+	assert.Panics(t, func() { CopyIntersectionStruct(&src{Name: "a", Age: 1}, &dst{}) })
+
+	type inner struct{ Name string }
+	type outer struct {
+		*inner
+	}
+	// Fields promoted through a nil embedded pointer cannot be read.
+	assert.Panics(t, func() { CopyIntersectionStruct(&outer{}, &dst{}) })
+	d := &dst{}
+	CopyIntersectionStruct(&outer{&inner{Name: "b"}}, d)
+	assert.Equal(t, "b", d.Name)
+}
+
 type complexSt struct {
 	A        aStruct
 	S        []string
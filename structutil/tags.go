@@ -0,0 +1,234 @@
+package structutil
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Tag is one key of a struct tag, e.g. `json:"name,omitempty"` has the key
+// "json", the name "name" and the option "omitempty".
+type Tag struct {
+	Key     string
+	Name    string
+	Options []string
+}
+
+// HasOption reports whether the tag has the given option.
+func (t *Tag) HasOption(option string) bool {
+	for _, o := range t.Options {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// AddOptions appends the options the tag does not have yet.
+func (t *Tag) AddOptions(options ...string) {
+	for _, o := range options {
+		if !t.HasOption(o) {
+			t.Options = append(t.Options, o)
+		}
+	}
+}
+
+// DeleteOptions removes the given options from the tag.
+func (t *Tag) DeleteOptions(options ...string) {
+	kept := t.Options[:0]
+	for _, o := range t.Options {
+		if !containsString(options, o) {
+			kept = append(kept, o)
+		}
+	}
+	t.Options = kept
+}
+
+// Value returns the tag value as written between the quotes, e.g. "name,omitempty".
+func (t *Tag) Value() string {
+	if len(t.Options) == 0 {
+		return t.Name
+	}
+	return t.Name + "," + strings.Join(t.Options, ",")
+}
+
+// String returns the tag as written in a struct, e.g. `json:"name,omitempty"`.
+func (t *Tag) String() string {
+	return t.Key + ":" + strconv.Quote(t.Value())
+}
+
+// Tags is a parsed struct tag, keeping the order of its keys.
+type Tags struct {
+	tags []*Tag
+}
+
+// ParseTags parses a struct tag such as `json:"name,omitempty" yaml:"name"`
+// following the conventions of reflect.StructTag. On a syntax error it
+// returns the keys parsed so far together with the error, matching
+// reflect.StructTag.Get, which ignores the rest of a malformed tag.
+func ParseTags(tag string) (*Tags, error) {
+	tags := &Tags{}
+	for tag != "" {
+		tag = strings.TrimLeft(tag, " ")
+		if tag == "" {
+			break
+		}
+		i := 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			return tags, fmt.Errorf("structutil: bad syntax for struct tag %q", tag)
+		}
+		key := tag[:i]
+		tag = tag[i+1:]
+
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			return tags, fmt.Errorf("structutil: unterminated value for struct tag key %q", key)
+		}
+		value, err := strconv.Unquote(tag[:i+1])
+		if err != nil {
+			return tags, fmt.Errorf("structutil: bad value for struct tag key %q: %w", key, err)
+		}
+		tag = tag[i+1:]
+		tags.tags = append(tags.tags, newTag(key, value))
+	}
+	return tags, nil
+}
+
+func newTag(key, value string) *Tag {
+	parts := strings.Split(value, ",")
+	t := &Tag{Key: key, Name: parts[0]}
+	if len(parts) > 1 {
+		t.Options = parts[1:]
+	}
+	return t
+}
+
+// Get returns the first tag with the given key. Modifying it modifies tags.
+func (t *Tags) Get(key string) (*Tag, bool) {
+	for _, tag := range t.tags {
+		if tag.Key == key {
+			return tag, true
+		}
+	}
+	return nil, false
+}
+
+// Lookup returns the value of the given key as reflect.StructTag.Lookup does.
+func (t *Tags) Lookup(key string) (string, bool) {
+	if tag, ok := t.Get(key); ok {
+		return tag.Value(), true
+	}
+	return "", false
+}
+
+// Set replaces the tag with the same key, or appends it.
+func (t *Tags) Set(tag *Tag) {
+	for i, existing := range t.tags {
+		if existing.Key == tag.Key {
+			t.tags[i] = tag
+			return
+		}
+	}
+	t.tags = append(t.tags, tag)
+}
+
+// SetValue parses value as the value of key and sets it, e.g.
+// SetValue("json", "name,omitempty").
+func (t *Tags) SetValue(key, value string) {
+	t.Set(newTag(key, value))
+}
+
+// Delete removes all tags with the given keys.
+func (t *Tags) Delete(keys ...string) {
+	kept := t.tags[:0]
+	for _, tag := range t.tags {
+		if !containsString(keys, tag.Key) {
+			kept = append(kept, tag)
+		}
+	}
+	t.tags = kept
+}
+
+// Keys returns the keys in order.
+func (t *Tags) Keys() []string {
+	keys := make([]string, len(t.tags))
+	for i, tag := range t.tags {
+		keys[i] = tag.Key
+	}
+	return keys
+}
+
+// Tags returns the parsed tags in order.
+func (t *Tags) Tags() []*Tag {
+	return t.tags
+}
+
+// Len returns the number of keys.
+func (t *Tags) Len() int {
+	return len(t.tags)
+}
+
+// String returns the struct tag, e.g. `json:"name,omitempty" yaml:"name"`,
+// suitable for reflect.StructField.Tag.
+func (t *Tags) String() string {
+	parts := make([]string, len(t.tags))
+	for i, tag := range t.tags {
+		parts[i] = tag.String()
+	}
+	return strings.Join(parts, " ")
+}
+
+// TaggedField is a struct field carrying a given tag key.
+type TaggedField struct {
+	Name  string // dotted path for fields of embedded structs, e.g. "Base.ID"
+	Index []int  // index sequence for reflect.Value.FieldByIndex
+	Tag   *Tag
+}
+
+// FieldsByTag lists the exported fields of the struct (or struct pointer) v
+// that have the tag key, in declaration order. Fields of embedded structs
+// that do not carry the key themselves are included as well.
+func FieldsByTag(v interface{}, key string) []TaggedField {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	return appendTaggedFields(nil, t, key, "", nil)
+}
+
+func appendTaggedFields(result []TaggedField, t reflect.Type, key, path string, index []int) []TaggedField {
+	for _, field := range cachedType(t).fields {
+		fieldIndex := append(append([]int(nil), index...), field.index...)
+		tag, ok := field.tags.Get(key)
+		switch {
+		case ok && field.exported:
+			tag = &Tag{Key: tag.Key, Name: tag.Name, Options: append([]string(nil), tag.Options...)}
+			result = append(result, TaggedField{Name: fieldPath(path, field.name), Index: fieldIndex, Tag: tag})
+		case !ok && field.anonymous && field.typ.Kind() == reflect.Struct:
+			result = appendTaggedFields(result, field.typ, key, fieldPath(path, field.name), fieldIndex)
+		}
+	}
+	return result
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
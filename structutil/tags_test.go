@@ -0,0 +1,65 @@
+package structutil
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTags(t *testing.T) {
+	tags, err := ParseTags(`json:"name,omitempty" yaml:"name"  validate:"oneof=a b,required" note:"say \"hi\""`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"json", "yaml", "validate", "note"}, tags.Keys())
+
+	json, ok := tags.Get("json")
+	assert.True(t, ok)
+	assert.Equal(t, &Tag{Key: "json", Name: "name", Options: []string{"omitempty"}}, json)
+	assert.True(t, json.HasOption("omitempty"))
+	assert.False(t, json.HasOption("string"))
+	value, ok := tags.Lookup("note")
+	assert.True(t, ok)
+	assert.Equal(t, `say "hi"`, value)
+	_, ok = tags.Get("xml")
+	assert.False(t, ok)
+
+	json.AddOptions("string", "omitempty")
+	json.DeleteOptions("omitempty")
+	tags.SetValue("xml", "Name,attr")
+	tags.Delete("validate", "yaml")
+	assert.Equal(t, `json:"name,string" note:"say \"hi\"" xml:"Name,attr"`, tags.String())
+	assert.Equal(t, reflect.StructTag(tags.String()).Get("xml"), "Name,attr")
+
+	for _, bad := range []string{`json`, `json:name`, `json:"name`, `:"x"`} {
+		_, err := ParseTags(bad)
+		assert.Error(t, err, bad)
+	}
+	tags, err = ParseTags(`json:"a" bad yaml:"b"`)
+	assert.Error(t, err)
+	assert.Equal(t, []string{"json"}, tags.Keys())
+}
+
+type tagsBase struct {
+	ID      string `env:"ID"`
+	Created string
+}
+
+type tagsConfig struct {
+	tagsBase
+	Host   string `env:"HOST,required"`
+	Port   int    `json:"port"`
+	secret string `env:"SECRET"`
+}
+
+func TestFieldsByTag(t *testing.T) {
+	fields := FieldsByTag(&tagsConfig{}, "env")
+	assert.Equal(t, []TaggedField{
+		{Name: "tagsBase.ID", Index: []int{0, 0}, Tag: &Tag{Key: "env", Name: "ID"}},
+		{Name: "Host", Index: []int{1}, Tag: &Tag{Key: "env", Name: "HOST", Options: []string{"required"}}},
+	}, fields)
+
+	// The returned tags are copies of the cached ones.
+	fields[1].Tag.Name = "changed"
+	assert.Equal(t, "HOST", FieldsByTag(tagsConfig{}, "env")[1].Tag.Name)
+	assert.Nil(t, FieldsByTag(1, "env"))
+}
@@ -0,0 +1,355 @@
+package structutil
+
+import (
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValidateTagName is the struct tag key read by Validate.
+const ValidateTagName = "validate"
+
+// FieldError describes a single validation rule that a field failed.
+type FieldError struct {
+	Path  string      // field path, e.g. "Database.Replicas[1].Host"
+	Rule  string      // failed rule, e.g. "max"
+	Param string      // rule parameter, e.g. "65535"
+	Value interface{} // offending value
+}
+
+func (e *FieldError) Error() string {
+	var msg string
+	switch e.Rule {
+	case "required":
+		msg = "is required"
+	case "min":
+		msg = "must be at least " + e.Param
+	case "max":
+		msg = "must be at most " + e.Param
+	case "len":
+		msg = "must have length " + e.Param
+	case "oneof":
+		msg = "must be one of [" + e.Param + "]"
+	case "email":
+		msg = "must be a valid email address"
+	case "url":
+		msg = "must be a valid URL"
+	case "regexp":
+		msg = "must match " + e.Param
+	default:
+		msg = "failed rule " + e.Rule
+	}
+	if e.Rule == "required" {
+		return e.Path + ": " + msg
+	}
+	if s, ok := e.Value.(string); ok {
+		return fmt.Sprintf("%s: %q %s", e.Path, s, msg)
+	}
+	return fmt.Sprintf("%s: %v %s", e.Path, e.Value, msg)
+}
+
+// ValidationErrors aggregates every rule violation found by Validate.
+type ValidationErrors []*FieldError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validate checks v against the rules declared in `validate` struct tags and
+// returns ValidationErrors listing every violation, or nil if v is valid.
+// Nested structs, pointers, slices, arrays and maps are walked recursively.
+//
+// Supported rules (comma separated, regexp must come last):
+//
+//	required     value must not be zero; slices and maps must not be empty
+//	omitempty    skip the remaining rules when the value is zero
+//	min=N max=N  numeric bounds, or length bounds for strings, slices and maps
+//	len=N        exact length of a string, slice or map
+//	oneof=a b c  value must be one of the space separated options
+//	email        value must be a bare email address
+//	url          value must be an absolute URL
+//	regexp=RE    value must match the regular expression RE
+//
+// Bounds on time.Duration fields may be written as durations, e.g. min=1s.
+// Unknown rules, malformed parameters and rules that do not apply to the
+// type of their field are returned as errors rather than ValidationErrors.
+func Validate(v interface{}) error {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return fmt.Errorf("structutil: Validate called with nil %T", v)
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return fmt.Errorf("structutil: Validate expects a struct or struct pointer, got %T", v)
+	}
+	var errs ValidationErrors
+	if err := validateStruct(value, "", &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+type validateRule struct {
+	name  string
+	param string
+	check func(v reflect.Value, r *validateRule) bool
+	re    *regexp.Regexp
+	bound float64 // parsed param of min, max and len
+}
+
+type fieldRules struct {
+	name      string
+	required  bool
+	omitempty bool
+	rules     []*validateRule
+}
+
+// parseValidateTag parses the rules of a field of type t, rejecting rules
+// that do not apply to t and malformed parameters.
+func parseValidateTag(tag string, t reflect.Type, fr *fieldRules) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for tag != "" {
+		var item string
+		if strings.HasPrefix(tag, "regexp=") {
+			item, tag = tag, ""
+		} else if i := strings.IndexByte(tag, ','); i >= 0 {
+			item, tag = tag[:i], tag[i+1:]
+		} else {
+			item, tag = tag, ""
+		}
+		name, param := item, ""
+		if i := strings.IndexByte(item, '='); i >= 0 {
+			name, param = item[:i], item[i+1:]
+		}
+		r := &validateRule{name: name, param: param}
+		switch name {
+		case "":
+			continue
+		case "required":
+			fr.required = true
+			continue
+		case "omitempty":
+			fr.omitempty = true
+			continue
+		case "min", "max":
+			if !measurable(t) {
+				return fmt.Errorf("validation rule %q does not apply to %s", name, t)
+			}
+			bound, ok := parseBound(t, param)
+			if !ok {
+				return fmt.Errorf("invalid %s bound %q", name, param)
+			}
+			r.bound, r.check = bound, checkMin
+			if name == "max" {
+				r.check = checkMax
+			}
+		case "len":
+			switch t.Kind() {
+			case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+			default:
+				return fmt.Errorf("validation rule %q does not apply to %s", name, t)
+			}
+			n, err := strconv.Atoi(param)
+			if err != nil || n < 0 {
+				return fmt.Errorf("invalid len %q", param)
+			}
+			r.bound, r.check = float64(n), checkLen
+		case "oneof":
+			r.check = checkOneOf
+		case "email", "url", "regexp":
+			if t.Kind() != reflect.String {
+				return fmt.Errorf("validation rule %q does not apply to %s", name, t)
+			}
+			switch name {
+			case "email":
+				r.check = checkEmail
+			case "url":
+				r.check = checkURL
+			default:
+				re, err := regexp.Compile(param)
+				if err != nil {
+					return err
+				}
+				r.re = re
+				r.check = checkRegexp
+			}
+		default:
+			return fmt.Errorf("unknown validation rule %q", name)
+		}
+		fr.rules = append(fr.rules, r)
+	}
+	return nil
+}
+
+func validateStruct(value reflect.Value, path string, errs *ValidationErrors) error {
+	info := cachedType(value.Type())
+	if info.validateErr != nil {
+		return info.validateErr
+	}
+	for i := range info.fields {
+		fr := &info.fields[i].validate
+		field := value.Field(info.fields[i].index[0])
+		fPath := fieldPath(path, fr.name)
+		if isEmpty(field, defaultEmptyOptions) {
+			if fr.required {
+				*errs = append(*errs, &FieldError{Path: fPath, Rule: "required", Value: interfaceOf(field)})
+				continue
+			}
+			if fr.omitempty {
+				continue
+			}
+		}
+		target := field
+		for target.Kind() == reflect.Ptr && !target.IsNil() {
+			target = target.Elem()
+		}
+		if target.Kind() != reflect.Ptr {
+			for _, r := range fr.rules {
+				if !r.check(target, r) {
+					*errs = append(*errs, &FieldError{Path: fPath, Rule: r.name, Param: r.param, Value: interfaceOf(target)})
+				}
+			}
+		}
+		if err := validateNested(target, fPath, errs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// interfaceOf returns v as an interface{}, or nil if v was reached through
+// an unexported embedded field.
+func interfaceOf(v reflect.Value) interface{} {
+	if !v.CanInterface() {
+		return nil
+	}
+	return v.Interface()
+}
+
+// validateNested descends into containers that may hold structs.
+func validateNested(v reflect.Value, path string, errs *ValidationErrors) error {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return validateNested(v.Elem(), path, errs)
+	case reflect.Struct:
+		return validateStruct(v, path, errs)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := validateNested(v.Index(i), indexPath(path, i), errs); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := validateNested(iter.Value(), keyPath(path, iter.Key()), errs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// measure returns the number compared by min/max: the numeric value for
+// numbers and the length for strings, slices, arrays and maps.
+func measure(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.String:
+		return float64(len([]rune(v.String()))), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true
+	}
+	return 0, false
+}
+
+// measurable reports whether min and max apply to values of type t.
+func measurable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return true
+	}
+	return false
+}
+
+// parseBound parses the parameter of min or max for a field of type t.
+func parseBound(t reflect.Type, param string) (float64, bool) {
+	if t == durationType {
+		if d, err := time.ParseDuration(param); err == nil {
+			return float64(d), true
+		}
+	}
+	f, err := strconv.ParseFloat(param, 64)
+	if err != nil || math.IsNaN(f) {
+		return 0, false
+	}
+	return f, true
+}
+
+func checkMin(v reflect.Value, r *validateRule) bool {
+	n, _ := measure(v)
+	return n >= r.bound
+}
+
+func checkMax(v reflect.Value, r *validateRule) bool {
+	n, _ := measure(v)
+	return n <= r.bound
+}
+
+func checkLen(v reflect.Value, r *validateRule) bool {
+	n, _ := measure(v)
+	return n == r.bound
+}
+
+func checkOneOf(v reflect.Value, r *validateRule) bool {
+	s := fmt.Sprint(v.Interface())
+	for _, opt := range strings.Fields(r.param) {
+		if s == opt {
+			return true
+		}
+	}
+	return false
+}
+
+func checkEmail(v reflect.Value, _ *validateRule) bool {
+	addr, err := mail.ParseAddress(v.String())
+	return err == nil && addr.Address == v.String()
+}
+
+func checkURL(v reflect.Value, _ *validateRule) bool {
+	u, err := url.Parse(v.String())
+	return err == nil && u.Scheme != "" && (u.Host != "" || u.Opaque != "" || u.Path != "")
+}
+
+func checkRegexp(v reflect.Value, r *validateRule) bool {
+	return r.re.MatchString(v.String())
+}
@@ -0,0 +1,124 @@
+package structutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type validateReplica struct {
+	Host string `validate:"required"`
+	Port int    `validate:"min=1,max=65535"`
+}
+
+type validateConfig struct {
+	Name     string            `validate:"required,min=3"`
+	Format   string            `validate:"oneof=json logfmt"`
+	Email    string            `validate:"omitempty,email"`
+	Homepage string            `validate:"omitempty,url"`
+	Code     string            `validate:"omitempty,regexp=^[a-z]{2,3}$"`
+	Timeout  time.Duration     `validate:"min=1s,max=1m"`
+	Tags     []string          `validate:"max=2"`
+	Primary  *validateReplica  `validate:"required"`
+	Replicas []validateReplica `validate:"min=1"`
+	Named    map[string]validateReplica
+}
+
+func validConfig() validateConfig {
+	return validateConfig{
+		Name:     "service",
+		Format:   "json",
+		Email:    "ops@example.com",
+		Homepage: "https://example.com",
+		Code:     "cn",
+		Timeout:  5 * time.Second,
+		Primary:  &validateReplica{Host: "db0", Port: 5432},
+		Replicas: []validateReplica{{Host: "db1", Port: 5432}},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := validConfig()
+	assert.NoError(t, Validate(cfg))
+	assert.NoError(t, Validate(&cfg))
+
+	cfg.Name = "ab"
+	cfg.Format = "xml"
+	cfg.Email = "not an email"
+	cfg.Homepage = "/relative"
+	cfg.Code = "CN"
+	cfg.Timeout = time.Hour
+	cfg.Tags = []string{"a", "b", "c"}
+	cfg.Primary = nil
+	cfg.Replicas = []validateReplica{{Host: "db1", Port: 5432}, {Port: 70000}}
+	cfg.Named = map[string]validateReplica{"backup": {Host: "db2"}}
+
+	err := Validate(&cfg)
+	assert.Error(t, err)
+	errs, ok := err.(ValidationErrors)
+	assert.True(t, ok)
+
+	var paths []string
+	for _, fe := range errs {
+		paths = append(paths, fe.Path+" "+fe.Rule)
+	}
+	assert.Equal(t, []string{
+		"Name min",
+		"Format oneof",
+		"Email email",
+		"Homepage url",
+		"Code regexp",
+		"Timeout max",
+		"Tags max",
+		"Primary required",
+		"Replicas[1].Host required",
+		"Replicas[1].Port max",
+		"Named[backup].Port min",
+	}, paths)
+	assert.Contains(t, err.Error(), `Format: "xml" must be one of [json logfmt]`)
+	assert.Contains(t, err.Error(), "Replicas[1].Port: 70000 must be at most 65535")
+}
+
+func TestValidateInvalidInput(t *testing.T) {
+	assert.Error(t, Validate("not a struct"))
+	assert.Error(t, Validate((*validateConfig)(nil)))
+
+	type badTag struct {
+		Name string `validate:"unknown"`
+	}
+	err := Validate(badTag{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown validation rule "unknown"`)
+
+	// Malformed parameters and rules on unsupported kinds are errors too.
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{struct {
+			N int `validate:"min=abc"`
+		}{}, `invalid min bound "abc"`},
+		{struct {
+			D time.Duration `validate:"max=soon"`
+		}{}, `invalid max bound "soon"`},
+		{struct {
+			S string `validate:"len=x"`
+		}{}, `invalid len "x"`},
+		{struct {
+			N int `validate:"len=2"`
+		}{}, `validation rule "len" does not apply to int`},
+		{struct {
+			B *bool `validate:"min=1"`
+		}{}, `validation rule "min" does not apply to bool`},
+		{struct {
+			Tags []string `validate:"email"`
+		}{}, `validation rule "email" does not apply to []string`},
+	}
+	for _, tt := range tests {
+		err := Validate(tt.v)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package yamlutil
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// aliasExpansion follows the aliases of a document during a traversal with
+// the limits yaml.v3 applies when decoding: an alias to a value containing
+// it is an error, and so is a document growing too much through aliases,
+// like the "billion laughs" attack.
+type aliasExpansion struct {
+	active   map[*yaml.Node]bool // nodes being traversed
+	depth    int                 // aliases being traversed
+	nodes    int                 // nodes traversed
+	expanded int                 // nodes traversed through an alias
+}
+
+func newAliasExpansion() *aliasExpansion {
+	return &aliasExpansion{active: make(map[*yaml.Node]bool)}
+}
+
+// enter returns the node n refers to, found at path, before it is
+// traversed; leave must be called with n once it has been.
+func (a *aliasExpansion) enter(n *yaml.Node, path []pathSegment) (*yaml.Node, error) {
+	target := resolve(n)
+	if a.active[target] {
+		msg := "value contains itself through a merge key"
+		if n.Kind == yaml.AliasNode {
+			msg = fmt.Sprintf("alias *%s refers to a value containing it", n.Value)
+		}
+		return nil, &DecodeError{Line: n.Line, Column: n.Column, Path: joinSegments(path), Msg: msg}
+	}
+	if n.Kind == yaml.AliasNode {
+		a.depth++
+	}
+	a.nodes++
+	if a.depth > 0 {
+		a.expanded++
+	}
+	if a.expanded > 100 && a.nodes > 1000 && float64(a.expanded)/float64(a.nodes) > allowedAliasRatio(a.nodes) {
+		a.leave(n)
+		return nil, &DecodeError{Line: n.Line, Column: n.Column, Path: joinSegments(path),
+			Msg: "document contains excessive aliasing"}
+	}
+	a.active[target] = true
+	return target, nil
+}
+
+func (a *aliasExpansion) leave(n *yaml.Node) {
+	delete(a.active, resolve(n))
+	if n.Kind == yaml.AliasNode {
+		a.depth--
+	}
+}
+
+// allowedAliasRatio is the share of nodes that may come from aliases after
+// traversing nodes nodes, as in yaml.v3: almost all of them for small
+// documents, a tenth past four million nodes.
+func allowedAliasRatio(nodes int) float64 {
+	const low, high = 400000, 4000000
+	switch {
+	case nodes <= low:
+		return 0.99
+	case nodes >= high:
+		return 0.10
+	default:
+		return 0.99 - 0.89*float64(nodes-low)/float64(high-low)
+	}
+}
@@ -0,0 +1,786 @@
+package yamlutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ConvertOption configures ToJSON, ToTOML and FromTOML.
+type ConvertOption func(o *convertOptions)
+
+type convertOptions struct {
+	lossy bool
+}
+
+// WithLossyConversion converts values the target format cannot represent
+// instead of failing: timestamps and binary become strings in JSON,
+// non-string keys become strings, nulls are dropped from TOML and TOML
+// local times and datetimes become YAML strings.
+func WithLossyConversion() ConvertOption {
+	return func(o *convertOptions) {
+		o.lossy = true
+	}
+}
+
+// ToJSON converts a YAML document to indented JSON, keeping the order of
+// mapping keys. Values JSON cannot represent, such as timestamps, binary
+// data, non-string keys, .inf and .nan, are reported as *DecodeError
+// unless WithLossyConversion is given. Aliases are expanded; recursive
+// aliases and excessive aliasing are *DecodeError too.
+func ToJSON(data []byte, opts ...ConvertOption) ([]byte, error) {
+	c, root, err := newConverter("JSON", data, opts)
+	if err != nil {
+		return nil, err
+	}
+	v, err := c.value(root, nil)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	if err := writeJSON(&b, v, ""); err != nil {
+		return nil, err
+	}
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
+
+// FromJSON converts a JSON document to YAML, keeping the order of object
+// keys. Every JSON document converts without loss.
+func FromJSON(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	n, err := jsonNode(dec)
+	if err != nil {
+		return nil, fmt.Errorf("yamlutil: %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("yamlutil: more than one JSON value")
+	}
+	return EncodeWithOptions(n, WithIndent(2))
+}
+
+// ToTOML converts a YAML mapping to TOML. Keys keep their order, except
+// that TOML requires the plain values of a table before its subtables.
+// Values TOML cannot represent, such as nulls, binary data and non-string
+// keys, are reported as *DecodeError unless WithLossyConversion is given.
+// Aliases are expanded as by ToJSON.
+func ToTOML(data []byte, opts ...ConvertOption) ([]byte, error) {
+	c, root, err := newConverter("TOML", data, opts)
+	if err != nil {
+		return nil, err
+	}
+	v, err := c.value(root, nil)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(orderedMap)
+	if !ok && v != nil {
+		return nil, &DecodeError{Line: root.Line, Column: root.Column, Msg: "TOML documents must be mappings"}
+	}
+	w := &tomlWriter{}
+	if err := w.table(nil, m, ""); err != nil {
+		return nil, err
+	}
+	return w.buf.Bytes(), nil
+}
+
+// FromTOML converts a TOML document to YAML, keeping the order of keys.
+// TOML local datetimes and times have no YAML equivalent and are reported
+// as errors unless WithLossyConversion is given.
+func FromTOML(data []byte, opts ...ConvertOption) ([]byte, error) {
+	var m map[string]interface{}
+	md, err := toml.Decode(string(data), &m)
+	if err != nil {
+		return nil, fmt.Errorf("yamlutil: %w", err)
+	}
+	t := tomlReader{order: tomlOrder(md, m)}
+	for _, o := range opts {
+		o(&t.convertOptions)
+	}
+	n, err := t.node(m, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	return EncodeWithOptions(n, WithIndent(2))
+}
+
+// orderedMap is a mapping converted from YAML, in document order.
+type orderedMap []orderedPair
+
+type orderedPair struct {
+	key   string
+	value interface{}
+}
+
+// localDate is a YAML timestamp without a time, a local date in TOML.
+type localDate string
+
+// converter converts YAML nodes to the values written by writeJSON and
+// tomlWriter.
+type converter struct {
+	convertOptions
+	target  string // "JSON" or "TOML"
+	aliases *aliasExpansion
+}
+
+// newConverter parses the single YAML document in data.
+func newConverter(target string, data []byte, opts []ConvertOption) (*converter, *yaml.Node, error) {
+	c := &converter{target: target, aliases: newAliasExpansion()}
+	for _, o := range opts {
+		o(&c.convertOptions)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	var root *yaml.Node
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, newDecodeError("", err.Error())
+		}
+		if isEmptyDocument(&doc) {
+			continue
+		}
+		if root != nil {
+			return nil, nil, fmt.Errorf("yamlutil: cannot convert several YAML documents to %s", target)
+		}
+		root = doc.Content[0]
+	}
+	if root == nil {
+		root = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}
+	}
+	return c, root, nil
+}
+
+// lossy returns an error for a value that cannot be converted without loss,
+// or nil when lossy conversions are allowed.
+func (c *converter) lossy(n *yaml.Node, path []pathSegment, format string, args ...interface{}) error {
+	if c.convertOptions.lossy {
+		return nil
+	}
+	return &DecodeError{Line: n.Line, Column: n.Column, Path: joinSegments(path),
+		Msg: fmt.Sprintf(format, args...) + " in " + c.target}
+}
+
+func (c *converter) value(n *yaml.Node, path []pathSegment) (interface{}, error) {
+	target, err := c.aliases.enter(n, path)
+	if err != nil {
+		return nil, err
+	}
+	defer c.aliases.leave(n)
+	n = target
+	switch n.Kind {
+	case yaml.MappingNode:
+		return c.mapping(n, path)
+	case yaml.SequenceNode:
+		items := make([]interface{}, 0, len(n.Content))
+		for i, item := range n.Content {
+			itemPath := appendSegment(path, pathSegment{index: i, isIndex: true})
+			v, err := c.value(item, itemPath)
+			if err != nil {
+				return nil, err
+			}
+			if v == nil && c.target == "TOML" {
+				if err := c.lossy(item, itemPath, "null cannot be represented"); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			items = append(items, v)
+		}
+		return items, nil
+	}
+	return c.scalar(n, path)
+}
+
+func (c *converter) mapping(n *yaml.Node, path []pathSegment) (orderedMap, error) {
+	m := orderedMap{}
+	seen := make(map[string]bool)
+	for _, e := range entries(n) {
+		key := resolve(e[0])
+		keyPath := appendSegment(path, pathSegment{key: key.Value})
+		if key.Kind != yaml.ScalarNode {
+			return nil, &DecodeError{Line: key.Line, Column: key.Column, Path: joinSegments(path),
+				Msg: "mapping and sequence keys cannot be represented in " + c.target}
+		}
+		if tag := key.ShortTag(); tag != "!!str" {
+			if err := c.lossy(key, keyPath, "%s key %q cannot be represented", tag, key.Value); err != nil {
+				return nil, err
+			}
+		}
+		if seen[key.Value] {
+			return nil, &DecodeError{Line: key.Line, Column: key.Column, Path: joinSegments(keyPath),
+				Msg: fmt.Sprintf("duplicate key %q", key.Value)}
+		}
+		seen[key.Value] = true
+		v, err := c.value(e[1], keyPath)
+		if err != nil {
+			return nil, err
+		}
+		if v == nil && c.target == "TOML" {
+			if err := c.lossy(e[1], keyPath, "null cannot be represented"); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		m = append(m, orderedPair{key.Value, v})
+	}
+	return m, nil
+}
+
+func (c *converter) scalar(n *yaml.Node, path []pathSegment) (interface{}, error) {
+	switch tag := n.ShortTag(); tag {
+	case "!!null":
+		return nil, nil
+	case "!!str":
+		return n.Value, nil
+	case "!!bool", "!!int", "!!float":
+		var v interface{}
+		if err := n.Decode(&v); err != nil {
+			return nil, err
+		}
+		switch v := v.(type) {
+		case float64:
+			if (math.IsInf(v, 0) || math.IsNaN(v)) && c.target == "JSON" {
+				if err := c.lossy(n, path, "%s cannot be represented", n.Value); err != nil {
+					return nil, err
+				}
+				return n.Value, nil
+			}
+		case uint64:
+			if v > math.MaxInt64 && c.target == "TOML" {
+				return nil, &DecodeError{Line: n.Line, Column: n.Column, Path: joinSegments(path),
+					Msg: "integer " + n.Value + " overflows TOML's 64-bit integers"}
+			}
+		}
+		return v, nil
+	case "!!timestamp":
+		var t time.Time
+		if err := n.Decode(&t); err != nil {
+			return nil, err
+		}
+		dateOnly := len(strings.TrimSpace(n.Value)) == len("2006-01-02")
+		if c.target == "JSON" {
+			if err := c.lossy(n, path, "timestamp %s cannot be represented", n.Value); err != nil {
+				return nil, err
+			}
+			if dateOnly {
+				return t.Format("2006-01-02"), nil
+			}
+			return t.Format(time.RFC3339Nano), nil
+		}
+		if dateOnly {
+			return localDate(t.Format("2006-01-02")), nil
+		}
+		return t, nil
+	case "!!binary":
+		if err := c.lossy(n, path, "binary data cannot be represented"); err != nil {
+			return nil, err
+		}
+		return strings.Join(strings.Fields(n.Value), ""), nil
+	default:
+		if err := c.lossy(n, path, "tag %s cannot be represented", n.Tag); err != nil {
+			return nil, err
+		}
+		return n.Value, nil
+	}
+}
+
+// writeJSON writes v indented by two spaces per level.
+func writeJSON(b *bytes.Buffer, v interface{}, indent string) error {
+	switch v := v.(type) {
+	case orderedMap:
+		if len(v) == 0 {
+			b.WriteString("{}")
+			return nil
+		}
+		b.WriteString("{\n")
+		for i, p := range v {
+			b.WriteString(indent + "  ")
+			writeJSONString(b, p.key)
+			b.WriteString(": ")
+			if err := writeJSON(b, p.value, indent+"  "); err != nil {
+				return err
+			}
+			if i < len(v)-1 {
+				b.WriteByte(',')
+			}
+			b.WriteByte('\n')
+		}
+		b.WriteString(indent + "}")
+	case []interface{}:
+		if len(v) == 0 {
+			b.WriteString("[]")
+			return nil
+		}
+		b.WriteString("[\n")
+		for i, item := range v {
+			b.WriteString(indent + "  ")
+			if err := writeJSON(b, item, indent+"  "); err != nil {
+				return err
+			}
+			if i < len(v)-1 {
+				b.WriteByte(',')
+			}
+			b.WriteByte('\n')
+		}
+		b.WriteString(indent + "]")
+	case string:
+		writeJSONString(b, v)
+	case float64:
+		// Keep 1.0 a float for readers telling integers and floats apart.
+		b.WriteString(formatFloat(v))
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		b.Write(data)
+	}
+	return nil
+}
+
+func writeJSONString(b *bytes.Buffer, s string) {
+	// json.Marshal would escape <, > and &.
+	enc := json.NewEncoder(b)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	b.Truncate(b.Len() - 1) // the newline written by Encode
+}
+
+// jsonNode reads the next JSON value from dec as a YAML node.
+func jsonNode(dec *json.Decoder) (*yaml.Node, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok == '[' {
+			n := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			for dec.More() {
+				item, err := jsonNode(dec)
+				if err != nil {
+					return nil, err
+				}
+				n.Content = append(n.Content, item)
+			}
+			_, err := dec.Token()
+			return n, err
+		}
+		n := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		seen := make(map[string]bool)
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			k := key.(string)
+			if seen[k] {
+				return nil, fmt.Errorf("duplicate key %q", k)
+			}
+			seen[k] = true
+			value, err := jsonNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			n.Content = append(n.Content, strNode(k), value)
+		}
+		_, err := dec.Token()
+		return n, err
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(tok.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: tok.String()}, nil
+	case string:
+		return strNode(tok), nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(tok)}, nil
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+}
+
+// tomlWriter writes converted values as TOML.
+type tomlWriter struct {
+	buf bytes.Buffer
+}
+
+// table writes the table at path, under header unless it only holds
+// subtables.
+func (w *tomlWriter) table(path []string, m orderedMap, header string) error {
+	var simple, nested int
+	for _, p := range m {
+		if _, ok := tomlTable(p.value); ok {
+			nested++
+		} else {
+			simple++
+		}
+	}
+	if header != "" && (simple > 0 || nested == 0 || strings.HasPrefix(header, "[[")) {
+		if w.buf.Len() > 0 {
+			w.buf.WriteByte('\n')
+		}
+		w.buf.WriteString(header + "\n")
+	}
+	for _, p := range m {
+		if _, ok := tomlTable(p.value); ok {
+			continue
+		}
+		text, err := tomlValue(p.value)
+		if err != nil {
+			return err
+		}
+		w.buf.WriteString(tomlKey(p.key) + " = " + text + "\n")
+	}
+	for _, p := range m {
+		tables, ok := tomlTable(p.value)
+		if !ok {
+			continue
+		}
+		sub := append(append([]string(nil), path...), p.key)
+		keys := make([]string, len(sub))
+		for i, k := range sub {
+			keys[i] = tomlKey(k)
+		}
+		if t, ok := p.value.(orderedMap); ok {
+			if err := w.table(sub, t, "["+strings.Join(keys, ".")+"]"); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, t := range tables {
+			if err := w.table(sub, t, "[["+strings.Join(keys, ".")+"]]"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// tomlTable reports whether v is written as a table or an array of tables.
+func tomlTable(v interface{}) ([]orderedMap, bool) {
+	switch v := v.(type) {
+	case orderedMap:
+		return []orderedMap{v}, true
+	case []interface{}:
+		tables := make([]orderedMap, len(v))
+		for i, item := range v {
+			t, ok := item.(orderedMap)
+			if !ok {
+				return nil, false
+			}
+			tables[i] = t
+		}
+		return tables, len(v) > 0
+	}
+	return nil, false
+}
+
+var bareKeyRe = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func tomlKey(k string) string {
+	if bareKeyRe.MatchString(k) {
+		return k
+	}
+	return tomlString(k)
+}
+
+// tomlValue formats v inline.
+func tomlValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return tomlString(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float64:
+		switch {
+		case math.IsInf(v, 1):
+			return "inf", nil
+		case math.IsInf(v, -1):
+			return "-inf", nil
+		case math.IsNaN(v):
+			return "nan", nil
+		}
+		return formatFloat(v), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case localDate:
+		return string(v), nil
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			text, err := tomlValue(item)
+			if err != nil {
+				return "", err
+			}
+			items[i] = text
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case orderedMap:
+		pairs := make([]string, len(v))
+		for i, p := range v {
+			text, err := tomlValue(p.value)
+			if err != nil {
+				return "", err
+			}
+			pairs[i] = tomlKey(p.key) + " = " + text
+		}
+		if len(pairs) == 0 {
+			return "{}", nil
+		}
+		return "{ " + strings.Join(pairs, ", ") + " }", nil
+	}
+	return "", fmt.Errorf("yamlutil: cannot write %T as TOML", v)
+}
+
+// tomlString quotes s as a TOML basic string.
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// tomlReader converts decoded TOML values to YAML nodes.
+type tomlReader struct {
+	convertOptions
+	order map[string]int // position of each key in the document, by tomlOrder path
+}
+
+// tomlArray tracks the element of an array of tables the keys being read
+// belong to.
+type tomlArray struct {
+	index    int
+	consumed int // keys of the element read so far, for inline arrays
+}
+
+// tomlOrder returns the position of each key of the document. Keys are
+// identified by their path joined with NUL bytes, with the index of each
+// array of tables on the way, so that every table of an array keeps its own
+// key order.
+func tomlOrder(md toml.MetaData, m map[string]interface{}) map[string]int {
+	order := make(map[string]int)
+	arrays := make(map[string]*tomlArray)
+	for i, key := range md.Keys() {
+		path := ""
+		var value interface{} = m
+		for j, name := range key {
+			path += "\x00" + name
+			if table, ok := value.(map[string]interface{}); ok {
+				value = table[name]
+			} else {
+				value = nil
+			}
+			tables := tomlTables(value)
+			if j == len(key)-1 {
+				switch a := arrays[path]; {
+				case md.Type(key...) == "ArrayHash":
+					// Each [[key]] header starts the next table.
+					if a == nil {
+						a = &tomlArray{index: -1}
+						arrays[path] = a
+					}
+					a.index++
+				case a == nil && tables != nil:
+					arrays[path] = &tomlArray{index: -1}
+				}
+				break
+			}
+			a := arrays[path]
+			if a == nil || tables == nil {
+				continue
+			}
+			if md.Type(key[:j+1]...) != "ArrayHash" && j == len(key)-2 {
+				// Inline tables list their keys one table after the other.
+				for a.index < 0 || a.index < len(tables)-1 && a.consumed >= len(tables[a.index]) {
+					a.index, a.consumed = a.index+1, 0
+				}
+				a.consumed++
+			}
+			if a.index < 0 || a.index >= len(tables) {
+				break
+			}
+			value = tables[a.index]
+			path += "\x00" + strconv.Itoa(a.index)
+		}
+		if _, ok := order[path]; !ok {
+			order[path] = i
+		}
+	}
+	return order
+}
+
+// tomlTables returns the tables of an array of tables, or nil.
+func tomlTables(v interface{}) []map[string]interface{} {
+	switch v := v.(type) {
+	case []map[string]interface{}:
+		return v
+	case []interface{}:
+		tables := make([]map[string]interface{}, len(v))
+		for i, item := range v {
+			table, ok := item.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			tables[i] = table
+		}
+		if len(tables) == 0 {
+			return nil
+		}
+		return tables
+	}
+	return nil
+}
+
+// node converts v, found at the tomlOrder path prefix, to a YAML node.
+func (t *tomlReader) node(v interface{}, prefix string, path []pathSegment) (*yaml.Node, error) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		names := make([]string, 0, len(v))
+		for k := range v {
+			names = append(names, k)
+		}
+		sort.SliceStable(names, func(i, j int) bool {
+			return t.position(prefix, names[i], names[j])
+		})
+		n := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, k := range names {
+			value, err := t.node(v[k], prefix+"\x00"+k, appendSegment(path, pathSegment{key: k}))
+			if err != nil {
+				return nil, err
+			}
+			n.Content = append(n.Content, strNode(k), value)
+		}
+		return n, nil
+	case []map[string]interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = item
+		}
+		return t.node(items, prefix, path)
+	case []interface{}:
+		n := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for i, item := range v {
+			value, err := t.node(item, prefix+"\x00"+strconv.Itoa(i), appendSegment(path, pathSegment{index: i, isIndex: true}))
+			if err != nil {
+				return nil, err
+			}
+			n.Content = append(n.Content, value)
+		}
+		return n, nil
+	case string:
+		return strNode(v), nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(v)}, nil
+	case int64:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.FormatInt(v, 10)}, nil
+	case float64:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: yamlFloat(v)}, nil
+	case time.Time:
+		switch v.Location().String() {
+		case "date-local":
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!timestamp", Value: v.Format("2006-01-02")}, nil
+		case "datetime-local", "time-local":
+			layout := "2006-01-02T15:04:05.999999999"
+			if v.Location().String() == "time-local" {
+				layout = "15:04:05.999999999"
+			}
+			if !t.lossy {
+				return nil, &DecodeError{Path: joinSegments(path),
+					Msg: fmt.Sprintf("TOML local %s %s cannot be represented in YAML", strings.TrimSuffix(v.Location().String(), "-local"), v.Format(layout))}
+			}
+			return strNode(v.Format(layout)), nil
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!timestamp", Value: v.Format(time.RFC3339Nano)}, nil
+	}
+	return nil, fmt.Errorf("yamlutil: unexpected TOML value %T", v)
+}
+
+// position reports whether key a of the table at prefix comes before b.
+func (t *tomlReader) position(prefix string, a, b string) bool {
+	pa, okA := t.order[prefix+"\x00"+a]
+	pb, okB := t.order[prefix+"\x00"+b]
+	switch {
+	case okA && okB:
+		return pa < pb
+	case okA != okB:
+		return okA
+	}
+	return a < b
+}
+
+// yamlFloat formats f so that YAML reads it back as a float.
+func yamlFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return ".inf"
+	case math.IsInf(f, -1):
+		return "-.inf"
+	case math.IsNaN(f):
+		return ".nan"
+	}
+	return formatFloat(f)
+}
+
+// formatFloat formats a finite f so that it reads back as a float.
+func formatFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eE") {
+		s += ".0"
+	}
+	return s
+}
+
+// strNode returns a string node quoted like yaml.Marshal would, so that
+// strings such as "yes" or "0755" stay strings for YAML 1.1 readers too.
+func strNode(s string) *yaml.Node {
+	n := &yaml.Node{}
+	_ = n.Encode(s)
+	return n
+}
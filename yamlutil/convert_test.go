@@ -0,0 +1,288 @@
+package yamlutil
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const convertSource = `name: web
+port: 8080
+ratio: 1.0
+tags: [a, "b<c>"]
+db:
+  host: localhost
+  opts: {ssl: true}
+servers:
+  - name: a
+  - name: b
+`
+
+func TestToJSON(t *testing.T) {
+	out, err := ToJSON([]byte(convertSource))
+	assert.NoError(t, err)
+	assert.Equal(t, `{
+  "name": "web",
+  "port": 8080,
+  "ratio": 1.0,
+  "tags": [
+    "a",
+    "b<c>"
+  ],
+  "db": {
+    "host": "localhost",
+    "opts": {
+      "ssl": true
+    }
+  },
+  "servers": [
+    {
+      "name": "a"
+    },
+    {
+      "name": "b"
+    }
+  ]
+}
+`, string(out))
+
+	out, err = ToJSON([]byte("base: &b {x: 1}\nc:\n  <<: *b\n  y: []\nn: ~\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "{\n  \"base\": {\n    \"x\": 1\n  },\n  \"c\": {\n    \"y\": [],\n    \"x\": 1\n  },\n  \"n\": null\n}\n", string(out))
+
+	out, err = ToJSON(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "null\n", string(out))
+}
+
+func TestToJSONLossy(t *testing.T) {
+	tests := map[string]string{
+		"when: 2024-01-02T03:04:05Z\n": `1:7: timestamp 2024-01-02T03:04:05Z cannot be represented in JSON`,
+		"day: 2024-01-02\n":            `1:6: timestamp 2024-01-02 cannot be represented in JSON`,
+		"bin: !!binary aGk=\n":         `1:6: binary data cannot be represented in JSON`,
+		"1: one\n":                     `1:1: !!int key "1" cannot be represented in JSON`,
+		"x: .inf\n":                    `1:4: .inf cannot be represented in JSON`,
+		"x: !secret s\n":               `1:4: tag !secret cannot be represented in JSON`,
+	}
+	for src, msg := range tests {
+		_, err := ToJSON([]byte(src))
+		var de *DecodeError
+		assert.True(t, errors.As(err, &de), src)
+		assert.EqualError(t, err, msg)
+	}
+
+	out, err := ToJSON([]byte("when: 2024-01-02T03:04:05Z\nday: 2024-01-02\nbin: !!binary aGk=\n1: one\n"), WithLossyConversion())
+	assert.NoError(t, err)
+	assert.Equal(t, "{\n  \"when\": \"2024-01-02T03:04:05Z\",\n  \"day\": \"2024-01-02\",\n  \"bin\": \"aGk=\",\n  \"1\": \"one\"\n}\n", string(out))
+
+	// Never representable.
+	_, err = ToJSON([]byte("1: a\n\"1\": b\n"), WithLossyConversion())
+	assert.EqualError(t, err, `2:1: duplicate key "1"`)
+	_, err = ToJSON([]byte("[a]: b\n"), WithLossyConversion())
+	assert.Error(t, err)
+	_, err = ToJSON([]byte("a: 1\n---\nb: 2\n"))
+	assert.Error(t, err)
+	_, err = ToJSON([]byte("a: [1\n"))
+	assert.Error(t, err)
+}
+
+func TestFromJSON(t *testing.T) {
+	out, err := FromJSON([]byte(`{"z": 1, "a": [1.5, 2.0, "3", true, null], "o": {"k": "yes"}, "e": {}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "z: 1\na:\n  - 1.5\n  - 2.0\n  - \"3\"\n  - true\n  - null\no:\n  k: \"yes\"\ne: {}\n", string(out))
+
+	// JSON to YAML and back is lossless.
+	json := []byte("{\n  \"b\": \"x\",\n  \"a\": [\n    1,\n    2.5\n  ]\n}\n")
+	out, err = FromJSON(json)
+	assert.NoError(t, err)
+	back, err := ToJSON(out)
+	assert.NoError(t, err)
+	assert.Equal(t, string(json), string(back))
+
+	for _, bad := range []string{`{"a": 1, "a": 2}`, `{"a": `, `1 2`} {
+		_, err := FromJSON([]byte(bad))
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestToTOML(t *testing.T) {
+	out, err := ToTOML([]byte(convertSource + "when: 2024-01-02T03:04:05Z\nday: 2024-01-02\n\"a b\": \"q\\\"\"\nempty: {}\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, `name = "web"
+port = 8080
+ratio = 1.0
+tags = ["a", "b<c>"]
+when = 2024-01-02T03:04:05Z
+day = 2024-01-02
+"a b" = "q\""
+
+[db]
+host = "localhost"
+
+[db.opts]
+ssl = true
+
+[[servers]]
+name = "a"
+
+[[servers]]
+name = "b"
+
+[empty]
+`, string(out))
+
+	out, err = ToTOML([]byte("a:\n  b:\n    c: 1\nl: [{x: 1}, 2]\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "l = [{ x = 1 }, 2]\n\n[a.b]\nc = 1\n", string(out))
+
+	_, err = ToTOML([]byte("a: null\n"))
+	assert.EqualError(t, err, "1:4: null cannot be represented in TOML")
+	out, err = ToTOML([]byte("a: null\nb: [1, null]\n"), WithLossyConversion())
+	assert.NoError(t, err)
+	assert.Equal(t, "b = [1]\n", string(out))
+	_, err = ToTOML([]byte("[1, 2]\n"))
+	assert.Error(t, err)
+	_, err = ToTOML([]byte("a: 18446744073709551615\n"), WithLossyConversion())
+	assert.Error(t, err)
+}
+
+func TestFromTOML(t *testing.T) {
+	out, err := FromTOML([]byte(`z = 1
+a = 2.0
+when = 2024-01-02T03:04:05Z
+day = 2024-01-02
+
+[server]
+port = 80
+host = "h"
+
+[[users]]
+name = "b"
+id = 2
+
+[[users]]
+name = "a"
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, `z: 1
+a: 2.0
+when: 2024-01-02T03:04:05Z
+day: 2024-01-02
+server:
+  port: 80
+  host: h
+users:
+  - name: b
+    id: 2
+  - name: a
+`, string(out))
+
+	// TOML to YAML and back keeps values and types.
+	back, err := ToTOML(out)
+	assert.NoError(t, err)
+	again, err := FromTOML(back)
+	assert.NoError(t, err)
+	assert.Equal(t, string(out), string(again))
+
+	_, err = FromTOML([]byte("t = 07:32:00\n"))
+	var de *DecodeError
+	assert.True(t, errors.As(err, &de))
+	assert.Equal(t, "t", de.Path)
+	out, err = FromTOML([]byte("t = 07:32:00\nlt = 2024-01-02T07:32:00\n"), WithLossyConversion())
+	assert.NoError(t, err)
+	assert.Equal(t, "t: \"07:32:00\"\nlt: 2024-01-02T07:32:00\n", string(out))
+	_, err = FromTOML([]byte("a = \n"))
+	assert.Error(t, err)
+}
+
+func TestFromTOMLArrayOrder(t *testing.T) {
+	// Each table of an array keeps its own key order.
+	out, err := FromTOML([]byte(`[[arr]]
+z = 1
+y = 2
+
+[[arr.sub]]
+q = 1
+p = 2
+
+[[arr]]
+y = 3
+z = 4
+
+[[arr.sub]]
+p = 3
+q = 4
+
+[t]
+inline = [{z = 1, y = 2}, {y = 3, z = 4}, {}, {x = 5, z = 6}]
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, `arr:
+  - z: 1
+    "y": 2
+    sub:
+      - q: 1
+        p: 2
+  - "y": 3
+    z: 4
+    sub:
+      - p: 3
+        q: 4
+t:
+  inline:
+    - z: 1
+      "y": 2
+    - "y": 3
+      z: 4
+    - {}
+    - x: 5
+      z: 6
+`, string(out))
+}
+
+// aliasBomb returns a document growing tenfold through aliases on each of
+// levels levels.
+func aliasBomb(levels int) string {
+	var b strings.Builder
+	b.WriteString("l0: &l0 [x]\n")
+	for i := 1; i <= levels; i++ {
+		fmt.Fprintf(&b, "l%d: &l%d [", i, i)
+		for j := 0; j < 10; j++ {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(&b, "*l%d", i-1)
+		}
+		b.WriteString("]\n")
+	}
+	return b.String()
+}
+
+func TestToJSONAliases(t *testing.T) {
+	tests := map[string]string{
+		"y: &b {k: *b}\n":       `1:11: alias *b refers to a value containing it`,
+		"x: &a [1, [*a]]\n":     `1:12: alias *a refers to a value containing it`,
+		"a: &a {k: {<<: *a}}\n": `1:11: value contains itself through a merge key`,
+	}
+	for src, msg := range tests {
+		for _, convert := range []func([]byte, ...ConvertOption) ([]byte, error){ToJSON, ToTOML} {
+			_, err := convert([]byte(src))
+			var de *DecodeError
+			assert.True(t, errors.As(err, &de), src)
+			assert.EqualError(t, err, msg)
+		}
+	}
+
+	_, err := ToJSON([]byte(aliasBomb(9)))
+	assert.EqualError(t, err, "1:10: document contains excessive aliasing")
+	_, err = ToTOML([]byte(aliasBomb(9)))
+	assert.Error(t, err)
+
+	// A mapping merging itself adds no keys.
+	out, err := ToJSON([]byte("a: &a {k: 1, <<: *a}\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "{\n  \"a\": {\n    \"k\": 1\n  }\n}\n", string(out))
+}
@@ -0,0 +1,976 @@
+package yamlutil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrNotFound is returned, wrapped, when a path does not exist in a document.
+var ErrNotFound = errors.New("yamlutil: path not found")
+
+// Document is a YAML document edited in place: Set, Delete and SetComment
+// rewrite only the text of the values they touch, so comments, key order,
+// quoting and formatting everywhere else stay byte-for-byte intact.
+// Paths address mapping keys with dots and sequence items with indexes,
+// e.g. "services[0].image"; quote keys containing dots or brackets:
+// `metadata.labels["app.kubernetes.io/name"]`. Only the first document of
+// a multi-document file is edited; the others are kept as they are.
+type Document struct {
+	path    string
+	data    []byte
+	root    yaml.Node
+	lines   []int  // offset of the start of each line
+	newline string // "\r\n" for documents using Windows line endings
+}
+
+// ParseDocument parses data for editing.
+func ParseDocument(data []byte) (*Document, error) {
+	d := &Document{newline: "\n"}
+	if bytes.Contains(data, []byte("\r\n")) {
+		d.newline = "\r\n"
+	}
+	if err := d.parse(append([]byte(nil), data...)); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// LoadDocument reads a YAML file for editing; Save writes it back.
+func LoadDocument(filePath string) (*Document, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	d, err := ParseDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	d.path = filePath
+	return d, nil
+}
+
+func (d *Document) parse(data []byte) error {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return err
+	}
+	d.data, d.root = data, root
+	d.lines = append(d.lines[:0], 0)
+	for i, b := range data {
+		if b == '\n' {
+			d.lines = append(d.lines, i+1)
+		}
+	}
+	return nil
+}
+
+// Bytes returns the current text of the document.
+func (d *Document) Bytes() []byte {
+	return append([]byte(nil), d.data...)
+}
+
+// Save writes the document back to the file it was loaded from. The file
+// keeps its mode unless opts say otherwise.
+func (d *Document) Save(opts ...WriteOption) error {
+	if d.path == "" {
+		return errors.New("yamlutil: document was not loaded from a file")
+	}
+	return writeFile(d.path, d.data, opts)
+}
+
+// Get returns the value at path decoded into generic Go values. Aliases
+// and `<<` merge keys are followed.
+func (d *Document) Get(path string) (interface{}, error) {
+	segs, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	n := d.content()
+	for _, seg := range segs {
+		if n = child(n, seg); n == nil {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, path)
+		}
+	}
+	if n == nil {
+		return nil, nil
+	}
+	var v interface{}
+	if err := n.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// child returns the value of seg in n, following aliases and merge keys,
+// or nil.
+func child(n *yaml.Node, seg pathSegment) *yaml.Node {
+	for n != nil && n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	switch {
+	case n == nil:
+		return nil
+	case n.Kind == yaml.SequenceNode && seg.isIndex:
+		if seg.index < len(n.Content) {
+			return n.Content[seg.index]
+		}
+	case n.Kind == yaml.MappingNode && !seg.isIndex:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == seg.key && n.Content[i].ShortTag() != "!!merge" {
+				return n.Content[i+1]
+			}
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].ShortTag() != "!!merge" {
+				continue
+			}
+			// <<: *base or <<: [*a, *b]
+			merged := n.Content[i+1]
+			for merged.Kind == yaml.AliasNode {
+				merged = merged.Alias
+			}
+			sources := []*yaml.Node{merged}
+			if merged.Kind == yaml.SequenceNode {
+				sources = merged.Content
+			}
+			for _, src := range sources {
+				if v := child(src, seg); v != nil {
+					return v
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// content returns the root value of the document, or nil if it is empty.
+func (d *Document) content() *yaml.Node {
+	if len(d.root.Content) == 0 {
+		return nil
+	}
+	return d.root.Content[0]
+}
+
+// Set sets the value at path, which may be any value Encode accepts or a
+// *yaml.Node. Existing values keep their anchor and quoting style; missing
+// keys are appended to their mapping, and an index one past the end of a
+// sequence appends an item. Missing intermediate mappings are created.
+func (d *Document) Set(path string, value interface{}) error {
+	segs, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+	if len(segs) == 0 {
+		return errors.New("yamlutil: Set needs a non-empty path")
+	}
+	var node *yaml.Node
+	if n, ok := value.(*yaml.Node); ok {
+		node = copyNode(n, make(map[*yaml.Node]*yaml.Node))
+	} else {
+		node = &yaml.Node{}
+		if err := node.Encode(value); err != nil {
+			return err
+		}
+	}
+	chain, err := d.locate(segs)
+	if err != nil {
+		return err
+	}
+	if len(chain) == len(segs)+1 {
+		return d.replace(chain[len(chain)-1], node)
+	}
+
+	last := chain[len(chain)-1]
+	missing := segs[len(chain)-1:]
+	switch {
+	case last.node == nil:
+		// An empty document.
+		if missing[0].isIndex {
+			return fmt.Errorf("%w: %s", ErrNotFound, path)
+		}
+		wrapped, err := wrapValue(node, missing)
+		if err != nil {
+			return err
+		}
+		text, err := d.renderValue(wrapped, 0, "")
+		if err != nil {
+			return err
+		}
+		if len(d.data) > 0 && d.data[len(d.data)-1] != '\n' {
+			text = "\n" + text
+		}
+		return d.splice(textEdit{len(d.data), len(d.data), text + "\n"})
+	case isNull(last.node):
+		wrapped, err := wrapValue(node, missing)
+		if err != nil {
+			return err
+		}
+		return d.replace(last, wrapped)
+	case last.node.Kind == yaml.MappingNode && !missing[0].isIndex:
+		value, err := wrapValue(node, missing[1:])
+		if err != nil {
+			return err
+		}
+		return d.insertEntry(last, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: missing[0].key}, value)
+	case last.node.Kind == yaml.SequenceNode && missing[0].isIndex && missing[0].index == len(last.node.Content):
+		value, err := wrapValue(node, missing[1:])
+		if err != nil {
+			return err
+		}
+		return d.insertEntry(last, nil, value)
+	}
+	return fmt.Errorf("%w: %s", ErrNotFound, path)
+}
+
+// wrapValue nests n in new mappings and sequences for the given segments;
+// only index 0 can start a new sequence.
+func wrapValue(n *yaml.Node, segs []pathSegment) (*yaml.Node, error) {
+	for i := len(segs) - 1; i >= 0; i-- {
+		if segs[i].isIndex {
+			if segs[i].index != 0 {
+				return nil, fmt.Errorf("%w: %s", ErrNotFound, joinSegments(segs[:i+1]))
+			}
+			n = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{n}}
+			continue
+		}
+		key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: segs[i].key}
+		n = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{key, n}}
+	}
+	return n, nil
+}
+
+// Delete removes the mapping entry or sequence item at path together with
+// its head comment. A collection left empty is written as {} or [].
+func (d *Document) Delete(path string) error {
+	segs, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+	chain, err := d.locate(segs)
+	if err != nil {
+		return err
+	}
+	if len(chain) != len(segs)+1 {
+		return fmt.Errorf("%w: %s", ErrNotFound, path)
+	}
+	if len(segs) == 0 {
+		return errors.New("yamlutil: cannot delete the root of a document")
+	}
+	loc, parent := chain[len(chain)-1], chain[len(chain)-2]
+	step := 1
+	if loc.key != nil {
+		step = 2
+	}
+	if len(parent.node.Content) == step {
+		return d.replace(parent, &yaml.Node{Kind: parent.node.Kind, Tag: parent.node.Tag, Style: yaml.FlowStyle})
+	}
+	first := loc.pos - step + 1 // index of the key, or of the item
+	start := d.entryStart(loc)
+	end := d.end(loc.node, loc.indent, loc.flow)
+	next := first + step
+	switch {
+	case loc.flow && next < len(parent.node.Content):
+		// Remove up to the next entry, taking the comma along.
+		return d.splice(textEdit{start, d.start(parent.node.Content[next]), ""})
+	case loc.flow:
+		prevEnd := d.end(parent.node.Content[first-1], loc.indent, true)
+		return d.splice(textEdit{prevEnd, end, ""})
+	case !d.lineLeading(start):
+		// The entry shares its line with "- ": the next entry moves up.
+		return d.splice(textEdit{start, d.entryStart(d.sibling(parent, next)), ""})
+	}
+	start = d.commentsAbove(d.lineStart(start), d.column(start))
+	end = d.lineEnd(end)
+	if end < len(d.data) {
+		end++ // the newline
+	}
+	return d.splice(textEdit{start, end, ""})
+}
+
+// SetComment sets the comment at the end of the line holding the value at
+// path, or at the end of its key's line for nested mappings and sequences.
+// The leading "# " is added when missing; an empty comment removes it.
+func (d *Document) SetComment(path, comment string) error {
+	if strings.ContainsAny(comment, "\r\n") {
+		return errors.New("yamlutil: comments must be a single line")
+	}
+	if comment = strings.TrimSpace(comment); comment != "" && !strings.HasPrefix(comment, "#") {
+		comment = "# " + comment
+	}
+	segs, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+	chain, err := d.locate(segs)
+	if err != nil {
+		return err
+	}
+	if len(chain) != len(segs)+1 || chain[len(chain)-1].node == nil {
+		return fmt.Errorf("%w: %s", ErrNotFound, path)
+	}
+	loc := chain[len(chain)-1]
+	if loc.flow {
+		return fmt.Errorf("yamlutil: cannot comment %s inside a flow collection", path)
+	}
+	var at int // offset after which the comment goes
+	switch n := loc.node; {
+	case isBlockCollection(n) && loc.parent != nil:
+		at = d.indicator(loc) + 1
+		if d.lineStart(d.start(n.Content[0])) < at {
+			// `- name: api` leaves no room for a comment on the item itself.
+			return fmt.Errorf("yamlutil: cannot comment %s, which starts on the line of its \"-\"", path)
+		}
+		// After the anchor and tag, if any.
+		at = d.skipProperties(d.skipSpaces(at))
+		for d.data[at-1] == ' ' || d.data[at-1] == '\t' {
+			at--
+		}
+	case isBlockCollection(n):
+		return errors.New("yamlutil: cannot comment the root of a document")
+	case n.Kind == yaml.ScalarNode && n.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0:
+		// The comment goes on the header line: `key: | # comment`.
+		at = d.scanToken(d.skipProperties(d.start(n)), false)
+	default:
+		at = d.end(n, loc.indent, false)
+	}
+	lineEnd := d.lineEnd(at)
+	edit := textEdit{at, at, ""}
+	if i := d.findComment(at, lineEnd); i >= 0 {
+		edit.end = lineEnd
+		for i > at && (d.data[i-1] == ' ' || d.data[i-1] == '\t') {
+			i--
+		}
+		edit.start = i
+	}
+	if comment != "" {
+		edit.text = " " + comment
+	}
+	return d.splice(edit)
+}
+
+// location is where a value sits in the document.
+type location struct {
+	parent *yaml.Node // mapping or sequence holding the value, nil for the root
+	key    *yaml.Node // key of a mapping entry
+	node   *yaml.Node // the value, nil in an empty document
+	pos    int        // index of node in parent.Content
+	indent int        // column of the parent's entries, -1 for the root
+	flow   bool       // inside a flow collection
+}
+
+// locate follows segs from the root as far as they exist, returning the
+// locations of the root and of each value found. Paths through aliases
+// cannot be edited and are reported as errors.
+func (d *Document) locate(segs []pathSegment) ([]location, error) {
+	chain := []location{{node: d.content(), indent: -1}}
+	for i, seg := range segs {
+		cur := chain[len(chain)-1]
+		n := cur.node
+		if n == nil || isNull(n) {
+			return chain, nil
+		}
+		if n.Kind == yaml.AliasNode {
+			return nil, fmt.Errorf("yamlutil: %s is an alias; edit its anchor instead", joinSegments(segs[:i]))
+		}
+		loc := location{parent: n, indent: d.entryIndent(n), flow: cur.flow || n.Style&yaml.FlowStyle != 0}
+		switch {
+		case n.Kind == yaml.MappingNode && !seg.isIndex:
+			for j := 0; j+1 < len(n.Content); j += 2 {
+				if n.Content[j].Value == seg.key && n.Content[j].Kind == yaml.ScalarNode {
+					loc.key, loc.node, loc.pos = n.Content[j], n.Content[j+1], j+1
+					break
+				}
+			}
+		case n.Kind == yaml.SequenceNode && seg.isIndex:
+			if seg.index < len(n.Content) {
+				loc.node, loc.pos = n.Content[seg.index], seg.index
+			}
+		default:
+			return nil, fmt.Errorf("yamlutil: %s is not a %s", pathName(segs[:i]), collectionName(seg))
+		}
+		if loc.node == nil {
+			return chain, nil
+		}
+		chain = append(chain, loc)
+	}
+	return chain, nil
+}
+
+func pathName(segs []pathSegment) string {
+	if len(segs) == 0 {
+		return "the document root"
+	}
+	return joinSegments(segs)
+}
+
+func collectionName(seg pathSegment) string {
+	if seg.isIndex {
+		return "sequence"
+	}
+	return "mapping"
+}
+
+// sibling returns the location of the entry at index i of the parent of loc.
+func (d *Document) sibling(parent location, i int) location {
+	p := parent.node
+	loc := location{parent: p, node: p.Content[i], pos: i, indent: d.entryIndent(p), flow: parent.flow || p.Style&yaml.FlowStyle != 0}
+	if p.Kind == yaml.MappingNode {
+		loc.key, loc.node, loc.pos = p.Content[i], p.Content[i+1], i+1
+	}
+	return loc
+}
+
+func isNull(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode && n.ShortTag() == "!!null"
+}
+
+func isBlockCollection(n *yaml.Node) bool {
+	return (n.Kind == yaml.MappingNode || n.Kind == yaml.SequenceNode) && n.Style&yaml.FlowStyle == 0 && len(n.Content) > 0
+}
+
+// replace rewrites the value at loc with n.
+func (d *Document) replace(loc location, n *yaml.Node) error {
+	old := loc.node
+	if old.Anchor != "" && n.Anchor == "" {
+		// Aliases elsewhere still refer to the value.
+		n.Anchor = old.Anchor
+	}
+	if old.Kind == yaml.ScalarNode && n.Kind == yaml.ScalarNode && n.ShortTag() == "!!str" {
+		if n.Style == 0 {
+			n.Style = old.Style & (yaml.SingleQuotedStyle | yaml.DoubleQuotedStyle)
+		}
+		if !strings.HasPrefix(old.Tag, "!!") {
+			// Custom tags such as !secret stay on the value.
+			n.Tag, n.Style = old.Tag, n.Style|yaml.TaggedStyle
+		}
+	}
+	start, end := d.start(old), d.end(old, loc.indent, loc.flow)
+	if loc.parent == nil {
+		text, err := d.renderRoot(n)
+		if err != nil {
+			return err
+		}
+		return d.splice(d.terminate(textEdit{start, end, text}))
+	}
+	if loc.flow {
+		text, err := d.renderFlow(n)
+		if err != nil {
+			return err
+		}
+		return d.splice(textEdit{start, end, text})
+	}
+
+	at := d.indicator(loc)
+	text, err := d.renderValue(n, loc.indent, string(d.data[at]))
+	if err != nil {
+		return err
+	}
+	// Collections starting below the key, block scalars and other values
+	// spanning lines take the comment on the key's line.
+	head, rest := text, ""
+	if i := strings.Index(text, "\n"); i >= 0 {
+		head, rest = text[:i], text[i:]
+	}
+	header := strings.TrimSpace(head) == "" || strings.ContainsAny(strings.TrimLeft(head, " ")[:1], "|>")
+	block := rest != "" || header
+	edit := textEdit{start: at + 1, end: end, text: text}
+	var comment string
+	switch {
+	case isBlockCollection(old):
+		// Keep the comment on the key's line when the old value starts below it.
+		if d.lineStart(d.start(old.Content[0])) > at {
+			lineEnd := d.lineEnd(at)
+			if i := d.findComment(at+1, lineEnd); i >= 0 {
+				comment = string(d.data[i:lineEnd])
+			}
+		}
+		edit.end = d.lineEnd(end)
+	case old.Kind == yaml.ScalarNode && old.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0:
+		// Keep the comment after the old block scalar header.
+		lineEnd := d.lineEnd(at)
+		if i := d.findComment(at+1, lineEnd); i >= 0 {
+			comment = string(d.data[i:lineEnd])
+		}
+	case block:
+		// The comment after the old value moves up to the key's line.
+		lineEnd := d.lineEnd(end)
+		if i := d.findComment(end, lineEnd); i >= 0 {
+			comment, edit.end = string(d.data[i:lineEnd]), lineEnd
+		}
+	}
+	switch {
+	case comment == "":
+	case header:
+		// After the block scalar header, or alone on the key's line.
+		edit.text = head + " " + comment + rest
+	default:
+		edit.text = text + " " + comment
+	}
+	if block {
+		edit = d.terminate(edit)
+	}
+	return d.splice(edit)
+}
+
+// terminate ends the text of a multi-line edit reaching the end of the data
+// with a line break, which block scalars need to keep their final newline.
+func (d *Document) terminate(edit textEdit) textEdit {
+	if strings.Contains(edit.text, "\n") && !bytes.Contains(d.data[edit.end:], []byte("\n")) {
+		edit.text += "\n"
+	}
+	return edit
+}
+
+// insertEntry appends an entry to the mapping or sequence at loc; key is
+// nil for sequences.
+func (d *Document) insertEntry(loc location, key, value *yaml.Node) error {
+	coll := loc.node
+	if coll.Style&yaml.FlowStyle != 0 || len(coll.Content) == 0 {
+		text, err := d.renderFlow(value)
+		if err != nil {
+			return err
+		}
+		if key != nil {
+			keyText, err := d.emitter().key(key)
+			if err != nil {
+				return err
+			}
+			text = keyText + ": " + text
+		}
+		end := d.end(coll, loc.indent, loc.flow)
+		if len(coll.Content) == 0 {
+			// Before the closing bracket of [] or {}.
+			return d.splice(textEdit{end - 1, end - 1, text})
+		}
+		last := coll.Content[len(coll.Content)-1]
+		return d.splice(textEdit{d.end(last, -1, true), d.end(last, -1, true), ", " + text})
+	}
+	indent := d.entryIndent(coll)
+	last := d.lineEnd(d.end(coll.Content[len(coll.Content)-1], indent, false))
+	prefix := strings.Repeat(" ", indent) + "-"
+	if key != nil {
+		keyText, err := d.emitter().key(key)
+		if err != nil {
+			return err
+		}
+		prefix = strings.Repeat(" ", indent) + keyText + ":"
+	}
+	indicator := "-"
+	if key != nil {
+		indicator = ":"
+	}
+	text, err := d.renderValue(value, indent, indicator)
+	if err != nil {
+		return err
+	}
+	return d.splice(textEdit{last, last, "\n" + prefix + text})
+}
+
+// emitter returns an emitter following the indentation of the document.
+func (d *Document) emitter() *emitter {
+	o := &encodeOptions{indent: 2}
+	d.detectStyle(d.content(), o, map[string]bool{})
+	return &emitter{encodeOptions: o, flow: make(map[*yaml.Node]bool)}
+}
+
+// detectStyle looks for the first nested mapping and sequence to learn
+// the indentation width and sequence style of the document.
+func (d *Document) detectStyle(n *yaml.Node, o *encodeOptions, found map[string]bool) {
+	if n == nil || !isBlockCollection(n) || found["indent"] && found["sequence"] {
+		return
+	}
+	indent := d.entryIndent(n)
+	for i, c := range n.Content {
+		if n.Kind == yaml.MappingNode && i%2 == 0 || !isBlockCollection(c) {
+			continue
+		}
+		childIndent := d.entryIndent(c)
+		switch {
+		case n.Kind == yaml.MappingNode && c.Kind == yaml.MappingNode && !found["indent"]:
+			if width := childIndent - indent; width >= 2 && width <= 9 {
+				o.indent, found["indent"] = width, true
+			}
+		case n.Kind == yaml.MappingNode && c.Kind == yaml.SequenceNode && !found["sequence"]:
+			if childIndent == indent {
+				o.sequences = SequenceCompact
+			}
+			found["sequence"] = true
+		}
+		d.detectStyle(c, o, found)
+	}
+}
+
+// renderValue renders n as the value of an entry at indent, following the
+// indicator (":" or "-"): " value" on the same line, or a block starting
+// with a newline.
+func (d *Document) renderValue(n *yaml.Node, indent int, indicator string) (string, error) {
+	e := d.emitter()
+	prefix := strings.Repeat(" ", indent) + indicator
+	e.buf.WriteString(prefix)
+	childIndent := indent + e.indent
+	if n.Kind == yaml.SequenceNode && e.sequences == SequenceCompact {
+		childIndent = indent
+	}
+	if indicator == "" {
+		// A new root mapping of an empty document.
+		if err := e.block(n, 0, false); err != nil {
+			return "", err
+		}
+	} else if err := e.value(n, indent, childIndent, "", indicator == "-"); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(e.buf.String()[len(prefix):], "\n"), nil
+}
+
+func (d *Document) renderRoot(n *yaml.Node) (string, error) {
+	e := d.emitter()
+	if err := e.document(n); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(e.buf.String(), "\n"), nil
+}
+
+// renderFlow renders n on a single line in flow style.
+func (d *Document) renderFlow(n *yaml.Node) (string, error) {
+	flow := copyNode(n, make(map[*yaml.Node]*yaml.Node))
+	setFlowStyle(flow)
+	return d.emitter().render(flow, 0)
+}
+
+func setFlowStyle(n *yaml.Node) {
+	if n.Kind == yaml.MappingNode || n.Kind == yaml.SequenceNode {
+		n.Style |= yaml.FlowStyle
+	}
+	if n.Kind == yaml.ScalarNode && n.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		n.Style = yaml.DoubleQuotedStyle
+	}
+	for _, c := range n.Content {
+		setFlowStyle(c)
+	}
+}
+
+// textEdit replaces data[start:end] with text.
+type textEdit struct {
+	start, end int
+	text       string
+}
+
+// splice applies the edits, which must not overlap, and parses the result.
+func (d *Document) splice(edits ...textEdit) error {
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+	data := d.data
+	for _, e := range edits {
+		text := e.text
+		if d.newline != "\n" {
+			text = strings.ReplaceAll(text, "\n", d.newline)
+		}
+		edited := make([]byte, 0, len(data)-(e.end-e.start)+len(text))
+		edited = append(edited, data[:e.start]...)
+		edited = append(edited, text...)
+		data = append(edited, data[e.end:]...)
+	}
+	if err := d.parse(data); err != nil {
+		return fmt.Errorf("yamlutil: edit would produce invalid YAML: %w", err)
+	}
+	return nil
+}
+
+// The functions below locate the text of nodes. yaml.v3 only records
+// where a node starts, so the end of each node is found by scanning.
+
+// offset converts a 1-based line and column (counted in characters) to an
+// offset in the data.
+func (d *Document) offset(line, column int) int {
+	if line < 1 || line > len(d.lines) {
+		return len(d.data)
+	}
+	off := d.lines[line-1]
+	for c := 1; c < column && off < len(d.data); c++ {
+		_, size := utf8.DecodeRune(d.data[off:])
+		off += size
+	}
+	return off
+}
+
+// start returns the offset of n, including its anchor and tag.
+func (d *Document) start(n *yaml.Node) int {
+	return d.offset(n.Line, n.Column)
+}
+
+func (d *Document) lineStart(off int) int {
+	return bytes.LastIndexByte(d.data[:off], '\n') + 1
+}
+
+// lineEnd returns the offset of the line break ending the line of off.
+func (d *Document) lineEnd(off int) int {
+	i := bytes.IndexByte(d.data[off:], '\n')
+	if i < 0 {
+		return len(d.data)
+	}
+	end := off + i
+	if end > 0 && d.data[end-1] == '\r' {
+		end--
+	}
+	return end
+}
+
+// column returns the 0-based column of off, counted in characters.
+func (d *Document) column(off int) int {
+	return utf8.RuneCount(d.data[d.lineStart(off):off])
+}
+
+// lineLeading reports whether only spaces precede off on its line.
+func (d *Document) lineLeading(off int) bool {
+	return len(bytes.Trim(d.data[d.lineStart(off):off], " \t")) == 0
+}
+
+// entryIndent returns the column of the entries of a block collection.
+func (d *Document) entryIndent(n *yaml.Node) int {
+	if n.Kind == yaml.MappingNode && len(n.Content) > 0 {
+		return d.column(d.start(n.Content[0]))
+	}
+	if n.Kind == yaml.SequenceNode && len(n.Content) > 0 {
+		return d.column(d.dash(n.Content[0]))
+	}
+	return d.column(d.start(n))
+}
+
+// dash returns the offset of the "-" before a block sequence item.
+func (d *Document) dash(item *yaml.Node) int {
+	off := d.start(item)
+	for off > 0 && strings.IndexByte(" \t\r\n", d.data[off-1]) >= 0 {
+		off--
+	}
+	if off > 0 && d.data[off-1] == '-' {
+		return off - 1
+	}
+	return off
+}
+
+// indicator returns the offset of the ":" after the key of loc, or of the
+// "-" before a sequence item.
+func (d *Document) indicator(loc location) int {
+	if loc.key == nil {
+		return d.dash(loc.node)
+	}
+	off := d.end(loc.key, loc.indent, loc.flow)
+	for off < len(d.data) && d.data[off] != ':' {
+		off++
+	}
+	return off
+}
+
+// entryStart returns the offset where the entry of loc starts: its key, or
+// the "-" of a block sequence item.
+func (d *Document) entryStart(loc location) int {
+	switch {
+	case loc.key != nil:
+		return d.start(loc.key)
+	case loc.flow:
+		return d.start(loc.node)
+	}
+	return d.dash(loc.node)
+}
+
+// commentsAbove extends the line starting at off upwards over the comment
+// lines directly above it that are indented to column.
+func (d *Document) commentsAbove(off, column int) int {
+	for off > 0 {
+		prev := d.lineStart(off - 1)
+		line := d.data[prev : off-1]
+		trimmed := bytes.TrimLeft(line, " \t")
+		if !bytes.HasPrefix(trimmed, []byte("#")) || len(line)-len(trimmed) != column {
+			break
+		}
+		off = prev
+	}
+	return off
+}
+
+// findComment returns the offset of a "#" starting a comment in
+// data[from:to], or -1.
+func (d *Document) findComment(from, to int) int {
+	for i := from; i < to; i++ {
+		switch d.data[i] {
+		case '#':
+			if i == 0 || d.data[i-1] == ' ' || d.data[i-1] == '\t' {
+				return i
+			}
+		case '"', '\'':
+			// Quoted text may contain '#'.
+			i = d.scanQuoted(i) - 1
+		}
+	}
+	return -1
+}
+
+func (d *Document) skipSpaces(off int) int {
+	for off < len(d.data) && (d.data[off] == ' ' || d.data[off] == '\t') {
+		off++
+	}
+	return off
+}
+
+// skipProperties skips the anchor and tag at off.
+func (d *Document) skipProperties(off int) int {
+	for off < len(d.data) && (d.data[off] == '&' || d.data[off] == '!') {
+		off = d.skipSpaces(d.scanToken(off, false))
+	}
+	return off
+}
+
+// scanToken returns the end of the anchor, alias or tag starting at off.
+func (d *Document) scanToken(off int, flow bool) int {
+	for off < len(d.data) && strings.IndexByte(" \t\r\n", d.data[off]) < 0 && !(flow && strings.IndexByte(",]}", d.data[off]) >= 0) {
+		off++
+	}
+	return off
+}
+
+// end returns the offset just after the text of n, whose parent's entries
+// are at column indent.
+func (d *Document) end(n *yaml.Node, indent int, flow bool) int {
+	off := d.start(n)
+	switch {
+	case n.Kind == yaml.AliasNode:
+		return d.scanToken(off+1, flow)
+	case n.Kind == yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return off
+		}
+		return d.end(n.Content[0], -1, false)
+	case isBlockCollection(n):
+		return d.end(n.Content[len(n.Content)-1], d.entryIndent(n), false)
+	case isNull(n) && n.Value == "" && n.Style == 0:
+		// An empty value such as "key:" ends where it starts.
+		return off
+	}
+	off = d.skipProperties(off)
+	switch {
+	case n.Kind == yaml.MappingNode || n.Kind == yaml.SequenceNode:
+		return d.scanFlow(off)
+	case n.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0:
+		return d.scanQuoted(off)
+	case n.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0:
+		return d.scanBlockScalar(off, indent)
+	}
+	return d.scanPlain(off, indent, flow)
+}
+
+// scanQuoted returns the end of the quoted scalar starting at off.
+func (d *Document) scanQuoted(off int) int {
+	quote := d.data[off]
+	for i := off + 1; i < len(d.data); i++ {
+		switch {
+		case quote == '"' && d.data[i] == '\\':
+			i++
+		case d.data[i] == quote && quote == '\'' && i+1 < len(d.data) && d.data[i+1] == '\'':
+			i++
+		case d.data[i] == quote:
+			return i + 1
+		}
+	}
+	return len(d.data)
+}
+
+// scanFlow returns the end of the flow collection starting at off.
+func (d *Document) scanFlow(off int) int {
+	depth := 0
+	for i := off; i < len(d.data); i++ {
+		switch c := d.data[i]; c {
+		case '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		case '"', '\'':
+			i = d.scanQuoted(i) - 1
+		case '#':
+			if d.data[i-1] == ' ' || d.data[i-1] == '\t' || d.data[i-1] == '\n' {
+				i = d.lineEnd(i)
+			}
+		}
+	}
+	return len(d.data)
+}
+
+// scanBlockScalar returns the end of the literal or folded scalar whose
+// header starts at off: the end of its last line indented deeper than indent.
+func (d *Document) scanBlockScalar(off, indent int) int {
+	end := d.lineEnd(off)
+	for next := end; next < len(d.data); {
+		lineStart := bytes.IndexByte(d.data[next:], '\n')
+		if lineStart < 0 {
+			break
+		}
+		lineStart += next + 1
+		lineEnd := d.lineEnd(lineStart)
+		line := d.data[lineStart:lineEnd]
+		trimmed := bytes.TrimLeft(line, " ")
+		if len(trimmed) > 0 {
+			if len(line)-len(trimmed) <= indent {
+				break
+			}
+			end = lineEnd
+		}
+		next = lineEnd
+	}
+	return end
+}
+
+// scanPlain returns the end of the plain scalar starting at off, following
+// continuation lines indented deeper than indent.
+func (d *Document) scanPlain(off, indent int, flow bool) int {
+	end := off
+	for pos := off; ; {
+		lineEnd := d.lineEnd(pos)
+		i := pos
+		for ; i < lineEnd; i++ {
+			c := d.data[i]
+			if c == '#' && i > off && (d.data[i-1] == ' ' || d.data[i-1] == '\t') ||
+				c == ':' && (i+1 == lineEnd || d.data[i+1] == ' ' || d.data[i+1] == '\t' || flow && strings.IndexByte(",]}", d.data[i+1]) >= 0) ||
+				flow && strings.IndexByte(",]}", c) >= 0 {
+				break
+			}
+		}
+		j := i
+		for j > pos && (d.data[j-1] == ' ' || d.data[j-1] == '\t') {
+			j--
+		}
+		if j > pos {
+			end = j
+		}
+		if i < lineEnd || flow {
+			return end
+		}
+		// Look for a continuation line.
+		next := lineEnd
+		for {
+			nl := bytes.IndexByte(d.data[next:], '\n')
+			if nl < 0 {
+				return end
+			}
+			next += nl + 1
+			lineEnd = d.lineEnd(next)
+			line := d.data[next:lineEnd]
+			trimmed := bytes.TrimLeft(line, " \t")
+			if len(trimmed) == 0 {
+				continue
+			}
+			if len(line)-len(trimmed) <= indent || trimmed[0] == '#' {
+				return end
+			}
+			pos = next + len(line) - len(trimmed)
+			break
+		}
+	}
+}
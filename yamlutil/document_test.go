@@ -0,0 +1,226 @@
+package yamlutil
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const documentSource = `# Service configuration
+name: web   # the name
+port: 8080
+labels:
+  app: web # app label
+  tier: "frontend"
+base: &base
+  image: nginx
+  replicas: 2
+services:
+  - name: api
+    image: api:1
+  # the worker
+  - name: worker
+    image: worker:1
+prod:
+  <<: *base
+  replicas: 3
+flow: {a: 1, b: [1, 2]}
+script: |
+  echo hi
+empty:
+`
+
+// edit parses documentSource, applies f and returns the resulting text.
+func edit(t *testing.T, f func(d *Document) error) string {
+	d, err := ParseDocument([]byte(documentSource))
+	assert.NoError(t, err)
+	assert.NoError(t, f(d))
+	return string(d.Bytes())
+}
+
+func TestDocumentGet(t *testing.T) {
+	d, err := ParseDocument([]byte(documentSource))
+	assert.NoError(t, err)
+	tests := map[string]interface{}{
+		"name":              "web",
+		"labels.tier":       "frontend",
+		"services[1].image": "worker:1",
+		"prod.image":        "nginx", // through the merge key
+		"prod.replicas":     3,
+		"flow.b[1]":         2,
+		"script":            "echo hi\n",
+		"empty":             nil,
+	}
+	for path, want := range tests {
+		got, err := d.Get(path)
+		assert.NoError(t, err, path)
+		assert.Equal(t, want, got, path)
+	}
+
+	_, err = d.Get("services[2]")
+	assert.True(t, errors.Is(err, ErrNotFound))
+	_, err = d.Get("services[")
+	assert.Error(t, err)
+}
+
+func TestDocumentSet(t *testing.T) {
+	// Replacing a value keeps its line comment, quoting and the rest of the text.
+	assert.Equal(t, replaceLine(documentSource, "name: web   # the name", "name: api   # the name"),
+		edit(t, func(d *Document) error { return d.Set("name", "api") }))
+	assert.Equal(t, replaceLine(documentSource, `  tier: "frontend"`, `  tier: "backend"`),
+		edit(t, func(d *Document) error { return d.Set("labels.tier", "backend") }))
+	assert.Equal(t, replaceLine(documentSource, "flow: {a: 1, b: [1, 2]}", "flow: {a: 1, b: [1, 5], c: x}"),
+		edit(t, func(d *Document) error {
+			if err := d.Set("flow.b[1]", 5); err != nil {
+				return err
+			}
+			return d.Set("flow.c", "x")
+		}))
+	assert.Equal(t, replaceLine(documentSource, "  replicas: 2\n", "  replicas: 2\n  tag: v1\n"),
+		edit(t, func(d *Document) error { return d.Set("base.tag", "v1") }))
+	assert.Equal(t, replaceLine(documentSource, "script: |\n  echo hi\n", "script: echo bye\n"),
+		edit(t, func(d *Document) error { return d.Set("script", "echo bye") }))
+
+	// New items, keys and intermediate mappings.
+	assert.Equal(t, replaceLine(documentSource, "    image: worker:1\n", "    image: worker:1\n  - name: db\n"),
+		edit(t, func(d *Document) error { return d.Set("services[2]", map[string]string{"name": "db"}) }))
+	assert.Equal(t, documentSource+"a:\n  b:\n    - 1\n",
+		edit(t, func(d *Document) error { return d.Set("a.b[0]", 1) }))
+	assert.Equal(t, replaceLine(documentSource, "empty:\n", "empty:\n  x: 1\n"),
+		edit(t, func(d *Document) error { return d.Set("empty.x", 1) }))
+
+	d, err := ParseDocument([]byte(documentSource))
+	assert.NoError(t, err)
+	assert.Error(t, d.Set("name.first", "x"))
+	assert.True(t, errors.Is(d.Set("services[5]", "x"), ErrNotFound))
+	assert.Equal(t, documentSource, string(d.Bytes()))
+}
+
+func TestDocumentSetStyle(t *testing.T) {
+	tests := []struct {
+		src, path string
+		value     interface{}
+		want      string
+	}{
+		{"a: 1 # c\n", "a", map[string]int{"b": 2}, "a: # c\n  b: 2\n"},
+		{"a:\n  b: 1\n", "a", "s", "a: s\n"},
+		{"a: !secret x\n", "a", "z", "a: !secret z\n"},
+		{"a: &x 1\nb: *x\n", "a", 2, "a: &x 2\nb: *x\n"},
+		{"a:\n- x\n- y\n", "a[2]", "z", "a:\n- x\n- y\n- z\n"},
+		{"m:\n    k: 'v'\n", "m.n.o", true, "m:\n    k: 'v'\n    n:\n        o: true\n"},
+		{"a: 1\r\nb: 2\r\n", "c.d", 3, "a: 1\r\nb: 2\r\nc:\r\n  d: 3\r\n"},
+		{"", "a", 1, "a: 1\n"},
+		{"# only a comment", "a", 1, "# only a comment\na: 1\n"},
+	}
+	for _, tt := range tests {
+		d, err := ParseDocument([]byte(tt.src))
+		assert.NoError(t, err)
+		assert.NoError(t, d.Set(tt.path, tt.value), tt.src)
+		assert.Equal(t, tt.want, string(d.Bytes()), tt.src)
+	}
+}
+
+func TestDocumentSetBlockScalar(t *testing.T) {
+	tests := []struct {
+		src, path string
+		value     string
+		want      string
+	}{
+		// The line comment moves to the header, out of the scalar.
+		{"name: web   # the name\n", "name", "line1\nline2\n", "name: | # the name\n  line1\n  line2\n"},
+		// The final line break is written at the end of the data.
+		{"a: 1\nb: x", "b", "l1\nl2\n", "a: 1\nb: |\n  l1\n  l2\n"},
+		{"a: 1\nb: x # c", "b", "l1\nl2", "a: 1\nb: |- # c\n  l1\n  l2\n"},
+		{"a: |\n  x\n  y\nb: 1\n", "a", "z\n", "a: |\n  z\nb: 1\n"},
+		{"a: | # c\n  x\nb: 1\n", "a", "z\n", "a: | # c\n  z\nb: 1\n"},
+		{"a: | # c\n  x\n", "a", "z", "a: z # c\n"},
+	}
+	for _, tt := range tests {
+		d, err := ParseDocument([]byte(tt.src))
+		assert.NoError(t, err)
+		assert.NoError(t, d.Set(tt.path, tt.value), tt.src)
+		assert.Equal(t, tt.want, string(d.Bytes()), tt.src)
+		got, err := d.Get(tt.path)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.value, got, tt.src)
+	}
+}
+
+func TestDocumentDelete(t *testing.T) {
+	assert.Equal(t, replaceLine(documentSource, "port: 8080\n", ""),
+		edit(t, func(d *Document) error { return d.Delete("port") }))
+	// Head comments go with their entry.
+	assert.Equal(t, replaceLine(documentSource, "  # the worker\n  - name: worker\n    image: worker:1\n", ""),
+		edit(t, func(d *Document) error { return d.Delete("services[1]") }))
+	assert.Equal(t, replaceLine(documentSource, "  - name: api\n    image: api:1\n", "  - image: api:1\n"),
+		edit(t, func(d *Document) error { return d.Delete("services[0].name") }))
+	assert.Equal(t, replaceLine(documentSource, "flow: {a: 1, b: [1, 2]}", "flow: {a: 1, b: [2]}"),
+		edit(t, func(d *Document) error { return d.Delete("flow.b[0]") }))
+	assert.Equal(t, replaceLine(documentSource, "flow: {a: 1, b: [1, 2]}", "flow: {a: 1}"),
+		edit(t, func(d *Document) error { return d.Delete("flow.b") }))
+	assert.Equal(t, replaceLine(documentSource, "script: |\n  echo hi\n", ""),
+		edit(t, func(d *Document) error { return d.Delete("script") }))
+	assert.Equal(t, replaceLine(documentSource, "labels:\n  app: web # app label\n  tier: \"frontend\"\n", "labels: {}\n"),
+		edit(t, func(d *Document) error {
+			if err := d.Delete("labels.app"); err != nil {
+				return err
+			}
+			return d.Delete("labels.tier")
+		}))
+
+	d, err := ParseDocument([]byte(documentSource))
+	assert.NoError(t, err)
+	assert.True(t, errors.Is(d.Delete("missing"), ErrNotFound))
+	assert.Error(t, d.Delete(""))
+}
+
+func TestDocumentSetComment(t *testing.T) {
+	assert.Equal(t, replaceLine(documentSource, "port: 8080", "port: 8080 # http"),
+		edit(t, func(d *Document) error { return d.SetComment("port", "http") }))
+	assert.Equal(t, replaceLine(documentSource, "name: web   # the name", "name: web"),
+		edit(t, func(d *Document) error { return d.SetComment("name", "") }))
+	assert.Equal(t, replaceLine(documentSource, "  app: web # app label", "  app: web # application"),
+		edit(t, func(d *Document) error { return d.SetComment("labels.app", "# application") }))
+	assert.Equal(t, replaceLine(documentSource, "base: &base", "base: &base # defaults"),
+		edit(t, func(d *Document) error { return d.SetComment("base", "defaults") }))
+	assert.Equal(t, replaceLine(documentSource, "script: |", "script: | # shell"),
+		edit(t, func(d *Document) error { return d.SetComment("script", "shell") }))
+
+	d, err := ParseDocument([]byte(documentSource))
+	assert.NoError(t, err)
+	assert.Error(t, d.SetComment("services[0]", "first"))
+	assert.Error(t, d.SetComment("flow.a", "in flow"))
+	assert.Error(t, d.SetComment("port", "two\nlines"))
+}
+
+func TestDocumentSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(documentSource), 0600))
+
+	d, err := LoadDocument(path)
+	assert.NoError(t, err)
+	assert.NoError(t, d.Set("port", 9090))
+	assert.NoError(t, d.Save())
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, replaceLine(documentSource, "port: 8080", "port: 9090"), string(data))
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	d, err = ParseDocument(data)
+	assert.NoError(t, err)
+	assert.Error(t, d.Save())
+	_, err = LoadDocument(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+// replaceLine replaces the first occurrence of old in s.
+func replaceLine(s, old, new string) string {
+	return strings.Replace(s, old, new, 1)
+}
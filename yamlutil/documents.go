@@ -0,0 +1,86 @@
+package yamlutil
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DocumentReader decodes the documents of a multi-document YAML stream,
+// such as a Kubernetes manifest, one at a time.
+type DocumentReader struct {
+	dec   *yaml.Decoder
+	index int // number of documents read so far
+}
+
+// NewDocumentReader returns a DocumentReader reading from r.
+func NewDocumentReader(r io.Reader) *DocumentReader {
+	return &DocumentReader{dec: yaml.NewDecoder(r)}
+}
+
+// Next decodes the next document into ptr. Empty documents, such as the
+// one after a trailing "---", are skipped. Next returns io.EOF when there
+// are no more documents; other errors name the 1-based document number.
+func (d *DocumentReader) Next(ptr interface{}) error {
+	for {
+		var node yaml.Node
+		err := d.dec.Decode(&node)
+		if err == io.EOF {
+			return io.EOF
+		}
+		d.index++
+		if err == nil && isEmptyDocument(&node) {
+			continue
+		}
+		if err == nil {
+			err = node.Decode(ptr)
+		}
+		if err != nil {
+			return fmt.Errorf("yamlutil: document %d: %w", d.index, err)
+		}
+		return nil
+	}
+}
+
+// isEmptyDocument reports whether doc has no content at all, as opposed to
+// an explicit null.
+func isEmptyDocument(doc *yaml.Node) bool {
+	if len(doc.Content) == 0 {
+		return true
+	}
+	n := doc.Content[0]
+	return n.Kind == yaml.ScalarNode && n.ShortTag() == "!!null" && n.Value == ""
+}
+
+// DecodeAll decodes every non-empty document in r.
+func DecodeAll(r io.Reader) ([]interface{}, error) {
+	d := NewDocumentReader(r)
+	var docs []interface{}
+	for {
+		var doc interface{}
+		err := d.Next(&doc)
+		if errors.Is(err, io.EOF) {
+			return docs, nil
+		}
+		if err != nil {
+			return docs, err
+		}
+		docs = append(docs, doc)
+	}
+}
+
+// EncodeAll writes docs to w as a multi-document stream, separated by "---".
+func EncodeAll(w io.Writer, docs ...interface{}) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	enc := yaml.NewEncoder(w)
+	for i, doc := range docs {
+		if err := enc.Encode(doc); err != nil {
+			return fmt.Errorf("yamlutil: document %d: %w", i+1, err)
+		}
+	}
+	return enc.Close()
+}
@@ -0,0 +1,87 @@
+package yamlutil
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const manifests = `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+---
+`
+
+type manifest struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+}
+
+func TestDecodeAll(t *testing.T) {
+	docs, err := DecodeAll(strings.NewReader(manifests))
+	assert.NoError(t, err)
+	assert.Len(t, docs, 2)
+	assert.Equal(t, "Service", docs[0].(map[string]interface{})["kind"])
+	assert.Equal(t, "Deployment", docs[1].(map[string]interface{})["kind"])
+
+	docs, err = DecodeAll(strings.NewReader("a: 1\n---\n---\nnull\n---\nb: 2\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{map[string]interface{}{"a": 1}, nil, map[string]interface{}{"b": 2}}, docs)
+
+	docs, err = DecodeAll(strings.NewReader("a: 1\n---\nb: [\n"))
+	assert.Len(t, docs, 1)
+	assert.Contains(t, err.Error(), "yamlutil: document 2: ")
+
+	docs, err = DecodeAll(strings.NewReader("a: 1\n---\nb: !!int x\n"))
+	assert.Len(t, docs, 1)
+	assert.Contains(t, err.Error(), "yamlutil: document 2: ")
+
+	docs, err = DecodeAll(strings.NewReader(""))
+	assert.NoError(t, err)
+	assert.Empty(t, docs)
+}
+
+func TestDocumentReader(t *testing.T) {
+	r := NewDocumentReader(strings.NewReader(manifests))
+	var kinds []string
+	for {
+		var m manifest
+		err := r.Next(&m)
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		kinds = append(kinds, m.Kind)
+	}
+	assert.Equal(t, []string{"Service", "Deployment"}, kinds)
+}
+
+func TestEncodeAll(t *testing.T) {
+	var svc, deploy manifest
+	svc.APIVersion, svc.Kind, svc.Metadata.Name = "v1", "Service", "web"
+	deploy.APIVersion, deploy.Kind, deploy.Metadata.Name = "apps/v1", "Deployment", "web"
+
+	var buf bytes.Buffer
+	assert.NoError(t, EncodeAll(&buf, svc, deploy))
+	assert.Equal(t, "apiVersion: v1\nkind: Service\nmetadata:\n    name: web\n---\napiVersion: apps/v1\nkind: Deployment\nmetadata:\n    name: web\n", buf.String())
+
+	docs, err := DecodeAll(&buf)
+	assert.NoError(t, err)
+	assert.Len(t, docs, 2)
+
+	buf.Reset()
+	assert.NoError(t, EncodeAll(&buf))
+	assert.Empty(t, buf.String())
+}
@@ -20,9 +20,11 @@ func Encode(v interface{}) ([]byte, error) {
 	return yaml.Marshal(v)
 }
 
-// EncodePretty encodes data to pretty YAML bytes with indentation.
+// EncodePretty encodes data to pretty YAML bytes: two space indentation,
+// long strings folded at 80 columns and short lists of scalars in flow
+// style. See EncodeWithOptions for finer control.
 func EncodePretty(v interface{}) ([]byte, error) {
-	return yaml.Marshal(v)
+	return EncodeWithOptions(v, WithIndent(2), WithLineWidth(80), WithFlowSequences(8))
 }
 
 // EncodeString encodes data to YAML string.
@@ -0,0 +1,306 @@
+package yamlutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadOption configures ReadFileExpanded and DecodeExpanded.
+type LoadOption func(o *loadOptions)
+
+type loadOptions struct {
+	lookup    func(name string) (string, bool)
+	fileTags  bool
+	maxDepth  int
+	including []string // absolute paths of the files being loaded, outermost first
+}
+
+// WithEnvLookup sets the function used to look up variables, os.LookupEnv
+// by default.
+func WithEnvLookup(lookup func(name string) (string, bool)) LoadOption {
+	return func(o *loadOptions) {
+		o.lookup = lookup
+	}
+}
+
+// WithoutFileTags rejects !include and !file instead of reading files, for
+// documents from untrusted sources.
+func WithoutFileTags() LoadOption {
+	return func(o *loadOptions) {
+		o.fileTags = false
+	}
+}
+
+// WithMaxIncludeDepth limits how deeply !include can nest, 32 by default.
+func WithMaxIncludeDepth(depth int) LoadOption {
+	return func(o *loadOptions) {
+		o.maxDepth = depth
+	}
+}
+
+func newLoadOptions(opts []LoadOption) *loadOptions {
+	o := &loadOptions{lookup: os.LookupEnv, fileTags: true, maxDepth: 32}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// ReadFileExpanded is ReadFile with interpolation of scalar values:
+//
+//	${VAR}            the value of VAR, empty when unset
+//	${VAR:-default}   default when VAR is unset or empty
+//	${VAR:?message}   an error with message when VAR is unset or empty
+//	$${VAR}           a literal ${VAR}
+//
+// Keys and comments are left alone. Plain scalars are typed after
+// expansion, so `port: ${PORT}` decodes into an int field; quoted scalars
+// stay strings. Two tags read other files, relative to the file using them:
+//
+//	database: !include database.yaml   the content of another YAML file
+//	password: !file secrets/db.txt     the text of a file, without its final newline
+//
+// Include cycles are reported as errors. Errors locating a problem in a
+// file are *DecodeError.
+func ReadFileExpanded(filePath string, v interface{}, opts ...LoadOption) error {
+	o := newLoadOptions(opts)
+	root, err := o.load(filePath)
+	if err != nil {
+		return err
+	}
+	return decodeExpanded(root, v)
+}
+
+// DecodeExpanded is Decode with the interpolation of ReadFileExpanded;
+// files are read relative to the working directory.
+func DecodeExpanded(data []byte, v interface{}, opts ...LoadOption) error {
+	o := newLoadOptions(opts)
+	root, err := o.parse("", ".", data)
+	if err != nil {
+		return err
+	}
+	return decodeExpanded(root, v)
+}
+
+func decodeExpanded(root *yaml.Node, v interface{}) error {
+	if root == nil {
+		return nil
+	}
+	return root.Decode(v)
+}
+
+// load reads and expands the file at path.
+func (o *loadOptions) load(path string) (*yaml.Node, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for i, p := range o.including {
+		if p == abs {
+			chain := append(append([]string(nil), o.including[i:]...), abs)
+			return nil, fmt.Errorf("yamlutil: include cycle: %s", strings.Join(chain, " -> "))
+		}
+	}
+	if len(o.including) > o.maxDepth {
+		return nil, fmt.Errorf("yamlutil: includes nested deeper than %d", o.maxDepth)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	o.including = append(o.including, abs)
+	defer func() { o.including = o.including[:len(o.including)-1] }()
+	return o.parse(path, filepath.Dir(path), data)
+}
+
+// parse parses data, read from file, and expands its first document.
+func (o *loadOptions) parse(file, dir string, data []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, newDecodeError(file, err.Error())
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	x := expander{loadOptions: o, file: file, dir: dir}
+	if err := x.node(doc.Content[0], nil); err != nil {
+		return nil, err
+	}
+	return doc.Content[0], nil
+}
+
+// expander expands the nodes of one file.
+type expander struct {
+	*loadOptions
+	file string
+	dir  string // directory relative paths are resolved against
+}
+
+func (x *expander) node(n *yaml.Node, path []pathSegment) error {
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if err := x.node(n.Content[i+1], appendSegment(path, pathSegment{key: n.Content[i].Value})); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for i, c := range n.Content {
+			if err := x.node(c, appendSegment(path, pathSegment{index: i, isIndex: true})); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		return x.scalar(n, path)
+	}
+	// Aliases share the node of their anchor, expanded where it is defined.
+	return nil
+}
+
+func (x *expander) scalar(n *yaml.Node, path []pathSegment) error {
+	value, err := expandVars(n.Value, x.lookup)
+	if err != nil {
+		return x.errorf(n, path, "%v", err)
+	}
+	switch n.Tag {
+	case "!include", "!file":
+		if !x.fileTags {
+			return x.errorf(n, path, "%s is disabled", n.Tag)
+		}
+		if value == "" {
+			return x.errorf(n, path, "%s needs a file name", n.Tag)
+		}
+		file := value
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(x.dir, file)
+		}
+		if n.Tag == "!file" {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return x.errorf(n, path, "%v", err)
+			}
+			*n = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: strings.TrimSuffix(string(data), "\n"),
+				Line: n.Line, Column: n.Column}
+			return nil
+		}
+		included, err := x.load(file)
+		if err != nil {
+			if _, ok := err.(*DecodeError); ok {
+				return err
+			}
+			return x.errorf(n, path, "%v", err)
+		}
+		if included == nil {
+			included = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}
+		}
+		*n = *included
+		return nil
+	}
+	if value == n.Value {
+		return nil
+	}
+	n.Value = value
+	if n.Style&(yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle|yaml.TaggedStyle) == 0 {
+		// Type the plain scalar by its expanded value.
+		n.Tag = ""
+	}
+	return nil
+}
+
+func (x *expander) errorf(n *yaml.Node, path []pathSegment, format string, args ...interface{}) error {
+	return &DecodeError{File: x.file, Line: n.Line, Column: n.Column, Path: joinSegments(path), Msg: fmt.Sprintf(format, args...)}
+}
+
+// expandVars replaces the ${VAR} references in s.
+func expandVars(s string, lookup func(string) (string, bool)) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		switch {
+		case strings.HasPrefix(s[i:], "$${"):
+			b.WriteString("${")
+			i += 3
+		case strings.HasPrefix(s[i:], "${"):
+			end := closingBrace(s, i+2)
+			if end < 0 {
+				return "", fmt.Errorf("unterminated variable reference %q", s[i:])
+			}
+			value, err := expandVar(s[i+2:end], lookup)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+			i = end + 1
+		default:
+			b.WriteByte(s[i])
+			i++
+		}
+	}
+	return b.String(), nil
+}
+
+// closingBrace returns the index of the "}" closing the reference whose
+// content starts at start, allowing nested references in defaults.
+func closingBrace(s string, start int) int {
+	depth := 1
+	for i := start; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "${"):
+			depth++
+			i++
+		case s[i] == '}':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// expandVar expands the content of one reference: NAME, NAME:-default or
+// NAME:?message.
+func expandVar(ref string, lookup func(string) (string, bool)) (string, error) {
+	name, op, arg := ref, "", ""
+	if i := strings.Index(ref, ":"); i >= 0 && i+1 < len(ref) && (ref[i+1] == '-' || ref[i+1] == '?') {
+		name, op, arg = ref[:i], ref[i:i+2], ref[i+2:]
+	}
+	if !isVarName(name) {
+		return "", fmt.Errorf("invalid variable reference %q", "${"+ref+"}")
+	}
+	value, ok := lookup(name)
+	if ok && value != "" {
+		return value, nil
+	}
+	switch op {
+	case ":-":
+		return expandVars(arg, lookup)
+	case ":?":
+		msg, err := expandVars(arg, lookup)
+		if err != nil {
+			return "", err
+		}
+		if msg == "" {
+			msg = "not set"
+		}
+		return "", fmt.Errorf("%s: %s", name, msg)
+	}
+	return value, nil
+}
+
+func isVarName(name string) bool {
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		return false
+	}
+	for _, c := range name {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,150 @@
+package yamlutil
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testLookup(vars map[string]string) LoadOption {
+	return WithEnvLookup(func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	})
+}
+
+func TestExpandVars(t *testing.T) {
+	lookup := func(name string) (string, bool) {
+		v, ok := map[string]string{"HOST": "db", "EMPTY": ""}[name]
+		return v, ok
+	}
+	tests := map[string]string{
+		"plain":                 "plain",
+		"${HOST}":               "db",
+		"tcp://${HOST}:5432":    "tcp://db:5432",
+		"${MISSING}":            "",
+		"${MISSING:-local}":     "local",
+		"${EMPTY:-local}":       "local",
+		"${HOST:-local}":        "db",
+		"${MISSING:-${HOST}-2}": "db-2",
+		"$${HOST} costs $5":     "${HOST} costs $5",
+		"${HOST:?set the host}": "db",
+	}
+	for in, want := range tests {
+		got, err := expandVars(in, lookup)
+		assert.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+
+	_, err := expandVars("${MISSING:?set the host}", lookup)
+	assert.EqualError(t, err, "MISSING: set the host")
+	_, err = expandVars("${EMPTY:?}", lookup)
+	assert.EqualError(t, err, "EMPTY: not set")
+	_, err = expandVars("${HOST", lookup)
+	assert.Error(t, err)
+	_, err = expandVars("${1X}", lookup)
+	assert.Error(t, err)
+}
+
+type expandConfig struct {
+	Name     string            `yaml:"name"`
+	Port     int               `yaml:"port"`
+	Debug    bool              `yaml:"debug"`
+	Version  string            `yaml:"version"`
+	Password string            `yaml:"password"`
+	Labels   map[string]string `yaml:"labels"`
+	Database struct {
+		Host string `yaml:"host"`
+		Port int    `yaml:"port"`
+	} `yaml:"database"`
+}
+
+func TestReadFileExpanded(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0600))
+		return path
+	}
+	writeFile("conf/database.yaml", "host: ${DB_HOST:-localhost}\nport: 5432\n")
+	writeFile("conf/secrets/password.txt", "s3cret\n")
+	path := writeFile("conf/app.yaml", `# ${NOT_EXPANDED}
+name: ${APP_NAME:?APP_NAME is required}
+port: ${PORT}
+debug: ${DEBUG:-false}
+version: "${VERSION}"
+password: !file secrets/password.txt
+labels:
+  ${KEY}: ${KEY}
+database: !include database.yaml
+`)
+	vars := map[string]string{"APP_NAME": "web", "PORT": "8080", "VERSION": "1.10", "KEY": "k"}
+
+	var cfg expandConfig
+	assert.NoError(t, ReadFileExpanded(path, &cfg, testLookup(vars)))
+	assert.Equal(t, "web", cfg.Name)
+	assert.Equal(t, 8080, cfg.Port)
+	assert.False(t, cfg.Debug)
+	assert.Equal(t, "1.10", cfg.Version)
+	assert.Equal(t, "s3cret", cfg.Password)
+	assert.Equal(t, map[string]string{"${KEY}": "k"}, cfg.Labels)
+	assert.Equal(t, "localhost", cfg.Database.Host)
+	assert.Equal(t, 5432, cfg.Database.Port)
+
+	delete(vars, "APP_NAME")
+	err := ReadFileExpanded(path, &cfg, testLookup(vars))
+	var de *DecodeError
+	assert.True(t, errors.As(err, &de))
+	assert.Equal(t, &DecodeError{File: path, Line: 2, Column: 7, Path: "name", Msg: "APP_NAME: APP_NAME is required"}, de)
+
+	err = ReadFileExpanded(path, &cfg, WithoutFileTags(), testLookup(map[string]string{"APP_NAME": "web"}))
+	assert.True(t, errors.As(err, &de))
+	assert.Equal(t, "password", de.Path)
+	assert.Equal(t, "!file is disabled", de.Msg)
+
+	// Errors in included files name that file.
+	bad := writeFile("bad/main.yaml", "sub: !include sub.yaml\n")
+	writeFile("bad/sub.yaml", "list:\n  - ok\n  - ${X:?missing}\n")
+	err = ReadFileExpanded(bad, &cfg)
+	assert.True(t, errors.As(err, &de))
+	assert.Equal(t, filepath.Join(dir, "bad", "sub.yaml"), de.File)
+	assert.Equal(t, "list[1]", de.Path)
+	assert.Equal(t, 3, de.Line)
+
+	writeFile("bad/missing.yaml", "file: !file nope.txt\n")
+	assert.Error(t, ReadFileExpanded(filepath.Join(dir, "bad", "missing.yaml"), &cfg))
+	assert.Error(t, ReadFileExpanded(filepath.Join(dir, "none.yaml"), &cfg))
+}
+
+func TestReadFileExpandedCycle(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.yaml")
+	assert.NoError(t, os.WriteFile(a, []byte("b: !include b.yaml\n"), 0600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "b.yaml"), []byte("a: !include a.yaml\n"), 0600))
+
+	var v interface{}
+	err := ReadFileExpanded(a, &v)
+	var de *DecodeError
+	assert.True(t, errors.As(err, &de))
+	assert.Equal(t, "a", de.Path)
+	assert.True(t, strings.Contains(de.Msg, "include cycle: "+a+" -> "+filepath.Join(dir, "b.yaml")+" -> "+a), de.Msg)
+
+	// The same file may be included twice when it is not a cycle.
+	assert.NoError(t, os.WriteFile(a, []byte("x: !include c.yaml\ny: !include c.yaml\n"), 0600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "c.yaml"), []byte("[1, 2]\n"), 0600))
+	assert.NoError(t, ReadFileExpanded(a, &v))
+	assert.Equal(t, map[string]interface{}{"x": []interface{}{1, 2}, "y": []interface{}{1, 2}}, v)
+}
+
+func TestDecodeExpanded(t *testing.T) {
+	var v map[string]interface{}
+	assert.NoError(t, DecodeExpanded([]byte("base: &b ${N}\ncopy: *b\nq: '${N}'\n"), &v, testLookup(map[string]string{"N": "3"})))
+	assert.Equal(t, map[string]interface{}{"base": 3, "copy": 3, "q": "3"}, v)
+	assert.NoError(t, DecodeExpanded(nil, &v))
+	assert.Error(t, DecodeExpanded([]byte("a: [1\n"), &v))
+}
@@ -0,0 +1,264 @@
+package yamlutil
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MergeStrategy decides how Merge combines sequences found in both documents.
+type MergeStrategy int
+
+const (
+	// MergeReplaceLists replaces base sequences with overlay sequences.
+	MergeReplaceLists MergeStrategy = iota
+	// MergeAppendLists appends the items of overlay sequences to base sequences.
+	MergeAppendLists
+)
+
+// Merge deep merges overlay into base and returns the result, formatted
+// with the indentation of base and keeping its comments:
+//
+//   - mappings are merged key by key, keys new in overlay are appended
+//   - sequences are replaced or appended to depending on strategy
+//   - other values in overlay replace those in base
+//   - `key: !reset` removes key from the result, expanding the `<<` merge
+//     keys it is inherited through into explicit keys
+//   - `key: !override value` replaces the base value without merging
+//
+// Base values inherited through `<<` merge keys are copied before being
+// changed, so the anchors they come from are not affected; values of base
+// anchors themselves are changed in place, for every alias. Overlay is
+// parsed on its own, so it can use its own anchors but not those of base.
+// Only the first document of each is merged.
+func Merge(base, overlay []byte, strategy MergeStrategy) ([]byte, error) {
+	if strategy != MergeReplaceLists && strategy != MergeAppendLists {
+		return nil, fmt.Errorf("yamlutil: unknown merge strategy %d", strategy)
+	}
+	d, err := ParseDocument(base)
+	if err != nil {
+		return nil, fmt.Errorf("yamlutil: base: %w", err)
+	}
+	var over yaml.Node
+	if err := yaml.Unmarshal(overlay, &over); err != nil {
+		return nil, fmt.Errorf("yamlutil: overlay: %w", err)
+	}
+	root := copyNode(&d.root, make(map[*yaml.Node]*yaml.Node))
+	if len(over.Content) > 0 {
+		src, err := flatten(over.Content[0], newAliasExpansion(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("yamlutil: overlay: %w", err)
+		}
+		m := merger{strategy}
+		if len(root.Content) == 0 {
+			root = &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{m.clean(src)}}
+		} else {
+			m.merge(root.Content[0], src)
+		}
+	}
+	if len(root.Content) == 0 {
+		return nil, nil
+	}
+	e := d.emitter()
+	if err := e.document(root); err != nil {
+		return nil, err
+	}
+	return e.buf.Bytes(), nil
+}
+
+type merger struct {
+	strategy MergeStrategy
+}
+
+// merge merges src into dst.
+func (m merger) merge(dst, src *yaml.Node) {
+	switch {
+	case src.Tag == "!override":
+		replaceNode(dst, m.clean(src))
+	case dst.Kind == yaml.AliasNode && isCollection(src) && resolve(dst).Kind == src.Kind:
+		// Merge into a copy rather than into the anchored value.
+		target := copyNode(resolve(dst), make(map[*yaml.Node]*yaml.Node))
+		target.Anchor = ""
+		replaceNode(dst, target)
+		m.merge(dst, src)
+	case dst.Kind == yaml.MappingNode && src.Kind == yaml.MappingNode:
+		m.mergeMapping(dst, src)
+	case dst.Kind == yaml.SequenceNode && src.Kind == yaml.SequenceNode && m.strategy == MergeAppendLists:
+		for _, item := range src.Content {
+			if item.Tag != "!reset" {
+				dst.Content = append(dst.Content, m.clean(item))
+			}
+		}
+	default:
+		replaceNode(dst, m.clean(src))
+	}
+}
+
+func (m merger) mergeMapping(dst, src *yaml.Node) {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		j := findKey(dst, key.Value)
+		switch {
+		case value.Tag == "!reset":
+			if j < 0 && child(dst, pathSegment{key: key.Value}) != nil {
+				// Inherited keys can only be removed once explicit.
+				expandMerges(dst)
+				j = findKey(dst, key.Value)
+			}
+			if j >= 0 {
+				dst.Content = append(dst.Content[:j], dst.Content[j+2:]...)
+			}
+		case j >= 0:
+			m.merge(dst.Content[j+1], value)
+		default:
+			if inherited := child(dst, pathSegment{key: key.Value}); inherited != nil {
+				// The key comes from a `<<` merge key: override it on a copy.
+				own := copyNode(inherited, make(map[*yaml.Node]*yaml.Node))
+				own.Anchor = ""
+				m.merge(own, value)
+				dst.Content = append(dst.Content, key, own)
+				continue
+			}
+			dst.Content = append(dst.Content, key, m.clean(value))
+		}
+	}
+}
+
+// expandMerges replaces the `<<` merge keys of mapping n by copies of the
+// entries they add.
+func expandMerges(n *yaml.Node) {
+	explicit := make(map[string]bool)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].ShortTag() != "!!merge" {
+			explicit[n.Content[i].Value] = true
+		}
+	}
+	content := make([]*yaml.Node, 0, len(n.Content))
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].ShortTag() != "!!merge" {
+			content = append(content, n.Content[i], n.Content[i+1])
+			continue
+		}
+		for _, e := range entries(&yaml.Node{Kind: yaml.MappingNode, Content: n.Content[i : i+2]}) {
+			if !explicit[e[0].Value] {
+				explicit[e[0].Value] = true
+				value := copyNode(e[1], make(map[*yaml.Node]*yaml.Node))
+				value.Anchor = ""
+				content = append(content, copyNode(e[0], make(map[*yaml.Node]*yaml.Node)), value)
+			}
+		}
+	}
+	n.Content = content
+}
+
+// findKey returns the index of the key in mapping n, ignoring merge keys,
+// or -1.
+func findKey(n *yaml.Node, key string) int {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key && n.Content[i].ShortTag() != "!!merge" {
+			return i
+		}
+	}
+	return -1
+}
+
+func isCollection(n *yaml.Node) bool {
+	return n.Kind == yaml.MappingNode || n.Kind == yaml.SequenceNode
+}
+
+// clean removes the !reset entries and !override tags from an overlay
+// value added to the result.
+func (m merger) clean(n *yaml.Node) *yaml.Node {
+	if n.Tag == "!override" || n.Tag == "!reset" {
+		n.Tag, n.Style = "", n.Style&^yaml.TaggedStyle
+		n.Tag = n.ShortTag()
+	}
+	switch n.Kind {
+	case yaml.MappingNode:
+		content := n.Content[:0]
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i+1].Tag != "!reset" {
+				content = append(content, n.Content[i], m.clean(n.Content[i+1]))
+			}
+		}
+		n.Content = content
+	case yaml.SequenceNode:
+		content := n.Content[:0]
+		for _, item := range n.Content {
+			if item.Tag != "!reset" {
+				content = append(content, m.clean(item))
+			}
+		}
+		n.Content = content
+	}
+	return n
+}
+
+// replaceNode makes dst a copy of src in place, so aliases of dst see the
+// new value, keeping the anchor and comments of dst.
+func replaceNode(dst, src *yaml.Node) {
+	replaced := *src
+	replaced.Anchor = dst.Anchor
+	if dst.HeadComment != "" || dst.LineComment != "" || dst.FootComment != "" {
+		replaced.HeadComment, replaced.LineComment, replaced.FootComment = dst.HeadComment, dst.LineComment, dst.FootComment
+	}
+	replaced.Line, replaced.Column = dst.Line, dst.Column
+	*dst = replaced
+}
+
+// flatten returns a copy of n, found at path, with aliases replaced by
+// copies of their anchors and `<<` merge keys expanded into explicit keys.
+// Each alias gets its own copy, so merging into one leaves the others.
+func flatten(n *yaml.Node, aliases *aliasExpansion, path []pathSegment) (*yaml.Node, error) {
+	target, err := aliases.enter(n, path)
+	if err != nil {
+		return nil, err
+	}
+	defer aliases.leave(n)
+	n = target
+	c := *n
+	c.Anchor = ""
+	switch n.Kind {
+	case yaml.MappingNode:
+		c.Content = nil
+		explicit := make(map[string]bool)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].ShortTag() != "!!merge" {
+				explicit[n.Content[i].Value] = true
+			}
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			pairs := [][2]*yaml.Node{{n.Content[i], n.Content[i+1]}}
+			if n.Content[i].ShortTag() == "!!merge" {
+				// Merged keys take the place of the merge key.
+				pairs = nil
+				for _, e := range entries(&yaml.Node{Kind: yaml.MappingNode, Content: n.Content[i : i+2]}) {
+					if !explicit[e[0].Value] {
+						explicit[e[0].Value] = true
+						pairs = append(pairs, e)
+					}
+				}
+			}
+			for _, e := range pairs {
+				keyPath := appendSegment(path, pathSegment{key: e[0].Value})
+				key, err := flatten(e[0], aliases, keyPath)
+				if err != nil {
+					return nil, err
+				}
+				value, err := flatten(e[1], aliases, keyPath)
+				if err != nil {
+					return nil, err
+				}
+				c.Content = append(c.Content, key, value)
+			}
+		}
+	case yaml.SequenceNode:
+		c.Content = make([]*yaml.Node, len(n.Content))
+		for i, item := range n.Content {
+			if c.Content[i], err = flatten(item, aliases, appendSegment(path, pathSegment{index: i, isIndex: true})); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &c, nil
+}
@@ -0,0 +1,136 @@
+package yamlutil
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const mergeBase = `# App config
+defaults: &defaults
+  image: nginx # the image
+  resources:
+    cpu: 1
+    mem: 2
+services:
+  web:
+    <<: *defaults
+    ports:
+      - 80 # http
+  db:
+    image: postgres
+    env: [A, B]
+debug: false # keep off
+`
+
+const mergeOverlay = `services:
+  web:
+    resources:
+      cpu: 4
+    ports:
+      - 443
+  db:
+    env: !override [C]
+    extra: &x {a: 1}
+  api: *x
+debug: !reset
+new:
+  k: v
+  gone: !reset
+`
+
+func TestMerge(t *testing.T) {
+	out, err := Merge([]byte(mergeBase), []byte(mergeOverlay), MergeReplaceLists)
+	assert.NoError(t, err)
+	assert.Equal(t, `# App config
+defaults: &defaults
+  image: nginx # the image
+  resources:
+    cpu: 1
+    mem: 2
+services:
+  web:
+    <<: *defaults
+    ports:
+      - 443
+    resources:
+      cpu: 4
+      mem: 2
+  db:
+    image: postgres
+    env: [C]
+    extra: {a: 1}
+  api: {a: 1}
+new:
+  k: v
+`, string(out))
+
+	out, err = Merge([]byte(mergeBase), []byte(mergeOverlay), MergeAppendLists)
+	assert.NoError(t, err)
+	var v map[string]interface{}
+	assert.NoError(t, Decode(out, &v))
+	web := v["services"].(map[string]interface{})["web"].(map[string]interface{})
+	assert.Equal(t, []interface{}{80, 443}, web["ports"])
+	assert.Equal(t, "nginx", web["image"])
+	// !override replaces lists even when appending.
+	assert.Equal(t, []interface{}{"C"}, v["services"].(map[string]interface{})["db"].(map[string]interface{})["env"])
+	assert.Contains(t, string(out), "      - 80 # http\n      - 443\n")
+}
+
+func TestMergeAnchors(t *testing.T) {
+	// Changing an anchored value changes it for every alias.
+	out, err := Merge([]byte("base: &b\n  x: 1\ncopy: *b\n"), []byte("base:\n  x: 2\n"), MergeReplaceLists)
+	assert.NoError(t, err)
+	assert.Equal(t, "base: &b\n  x: 2\ncopy: *b\n", string(out))
+
+	// Merging into an alias copies the value first.
+	out, err = Merge([]byte("base: &b\n  x: 1\ncopy: *b\n"), []byte("copy:\n  y: 2\n"), MergeReplaceLists)
+	assert.NoError(t, err)
+	assert.Equal(t, "base: &b\n  x: 1\ncopy:\n  x: 1\n  y: 2\n", string(out))
+
+	// Resetting an inherited key expands the merge key without it.
+	out, err = Merge([]byte("base: &b\n  x: 1\n  y: [2]\nc:\n  <<: *b\n  z: 3\n"), []byte("c:\n  x: !reset\n"), MergeReplaceLists)
+	assert.NoError(t, err)
+	assert.Equal(t, "base: &b\n  x: 1\n  y: [2]\nc:\n  y: [2]\n  z: 3\n", string(out))
+
+	// Overlays can use merge keys of their own.
+	out, err = Merge([]byte("a: 1\n"), []byte("t: &t {x: 1}\nb:\n  <<: *t\n  y: 2\n"), MergeReplaceLists)
+	assert.NoError(t, err)
+	assert.Equal(t, "a: 1\nt: {x: 1}\nb:\n  x: 1\n  y: 2\n", string(out))
+}
+
+func TestMergeEdgeCases(t *testing.T) {
+	// The indentation of base is kept.
+	out, err := Merge([]byte("a:\n    b: 1\nl:\n- x\n"), []byte("a:\n  c: 2\nl: [y]\n"), MergeAppendLists)
+	assert.NoError(t, err)
+	assert.Equal(t, "a:\n    b: 1\n    c: 2\nl:\n- x\n- y\n", string(out))
+
+	out, err = Merge(nil, []byte("a: !override 1\nb: !reset\n"), MergeReplaceLists)
+	assert.NoError(t, err)
+	assert.Equal(t, "a: 1\n", string(out))
+
+	out, err = Merge([]byte("a: 1 # one\n"), nil, MergeReplaceLists)
+	assert.NoError(t, err)
+	assert.Equal(t, "a: 1 # one\n", string(out))
+
+	out, err = Merge([]byte("a: 1 # one\n"), []byte("a: {b: 2}\n"), MergeReplaceLists)
+	assert.NoError(t, err)
+	assert.Equal(t, "a: {b: 2} # one\n", string(out))
+
+	_, err = Merge([]byte("a: [1"), nil, MergeReplaceLists)
+	assert.Error(t, err)
+	_, err = Merge(nil, []byte("a: [1"), MergeReplaceLists)
+	assert.Error(t, err)
+	_, err = Merge(nil, nil, MergeStrategy(9))
+	assert.Error(t, err)
+
+	_, err = Merge([]byte("a: 1\n"), []byte("x: &a [*a]\n"), MergeReplaceLists)
+	assert.EqualError(t, err, "yamlutil: overlay: 1:8: alias *a refers to a value containing it")
+	var de *DecodeError
+	assert.True(t, errors.As(err, &de))
+	assert.Equal(t, "x[0]", de.Path)
+	_, err = Merge([]byte("a: 1\n"), []byte(aliasBomb(9)), MergeReplaceLists)
+	assert.True(t, errors.As(err, &de))
+	assert.Equal(t, "document contains excessive aliasing", de.Msg)
+}
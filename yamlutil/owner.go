@@ -0,0 +1,17 @@
+//go:build !windows
+// +build !windows
+
+package yamlutil
+
+import (
+	"os"
+	"syscall"
+)
+
+// chown gives f the owner and group of the file described by info. Only
+// privileged processes may give files away, so failures are ignored.
+func chown(f *os.File, info os.FileInfo) {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		_ = f.Chown(int(st.Uid), int(st.Gid))
+	}
+}
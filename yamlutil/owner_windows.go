@@ -0,0 +1,7 @@
+package yamlutil
+
+import "os"
+
+// chown does nothing on Windows, where new files inherit the permissions
+// of their directory.
+func chown(f *os.File, info os.FileInfo) {}
@@ -0,0 +1,106 @@
+package yamlutil
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pathSegment is one step of a key path such as `services[0].labels["app.name"]`.
+type pathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+func (s pathSegment) String() string {
+	if s.isIndex {
+		return "[" + strconv.Itoa(s.index) + "]"
+	}
+	return s.key
+}
+
+// parsePath splits a key path into segments: mapping keys separated by
+// dots, sequence indexes in brackets and keys containing dots or brackets
+// quoted in brackets, e.g. `metadata.labels["app.kubernetes.io/name"]`.
+func parsePath(path string) ([]pathSegment, error) {
+	var segs []pathSegment
+	for i := 0; i < len(path); {
+		switch path[i] {
+		case '.':
+			if i == 0 || i+1 == len(path) || path[i+1] == '.' || path[i+1] == '[' {
+				return nil, fmt.Errorf("yamlutil: invalid path %q", path)
+			}
+			i++
+		case '[':
+			end, seg, err := parseBracket(path, i)
+			if err != nil {
+				return nil, err
+			}
+			segs = append(segs, seg)
+			i = end
+		default:
+			end := i
+			for end < len(path) && path[end] != '.' && path[end] != '[' {
+				end++
+			}
+			segs = append(segs, pathSegment{key: path[i:end]})
+			i = end
+		}
+	}
+	return segs, nil
+}
+
+// parseBracket parses the bracketed segment starting at path[start] and
+// returns the offset just after it.
+func parseBracket(path string, start int) (int, pathSegment, error) {
+	end := start + 1
+	if end < len(path) && (path[end] == '"' || path[end] == '\'') {
+		quote := path[end]
+		for end++; end < len(path) && path[end] != quote; end++ {
+			if path[end] == '\\' && quote == '"' {
+				end++
+			}
+		}
+		if end+1 >= len(path) || path[end+1] != ']' {
+			return 0, pathSegment{}, fmt.Errorf("yamlutil: unterminated key in path %q", path)
+		}
+		key := path[start+2 : end]
+		if quote == '"' {
+			unquoted, err := strconv.Unquote(path[start+1 : end+1])
+			if err != nil {
+				return 0, pathSegment{}, fmt.Errorf("yamlutil: invalid key in path %q: %w", path, err)
+			}
+			key = unquoted
+		}
+		return end + 2, pathSegment{key: key}, nil
+	}
+	close := strings.IndexByte(path[start:], ']')
+	if close < 0 {
+		return 0, pathSegment{}, fmt.Errorf("yamlutil: unterminated index in path %q", path)
+	}
+	index, err := strconv.Atoi(path[start+1 : start+close])
+	if err != nil || index < 0 {
+		return 0, pathSegment{}, fmt.Errorf("yamlutil: invalid index %q in path %q", path[start+1:start+close], path)
+	}
+	return start + close + 1, pathSegment{index: index, isIndex: true}, nil
+}
+
+// joinSegments formats segments back into a path.
+func joinSegments(segs []pathSegment) string {
+	var b strings.Builder
+	for _, s := range segs {
+		switch {
+		case s.isIndex:
+			b.WriteString(s.String())
+		case s.key == "" || strings.ContainsAny(s.key, ".[]"):
+			b.WriteString("[" + strconv.Quote(s.key) + "]")
+		default:
+			if b.Len() > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(s.key)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,477 @@
+package yamlutil
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SequenceStyle selects how block sequences nested in mappings are indented.
+type SequenceStyle int
+
+const (
+	// SequenceIndented indents sequence items below their key:
+	//
+	//	ports:
+	//	  - 80
+	SequenceIndented SequenceStyle = iota
+	// SequenceCompact aligns sequence items with their key:
+	//
+	//	ports:
+	//	- 80
+	SequenceCompact
+)
+
+// QuoteStyle selects how string values are quoted.
+type QuoteStyle int
+
+const (
+	QuoteAuto   QuoteStyle = iota // quote only strings that would otherwise be misread
+	QuoteSingle                   // 'single quoted'
+	QuoteDouble                   // "double quoted"
+)
+
+// EncodeOption customises EncodeWithOptions.
+type EncodeOption func(o *encodeOptions)
+
+type encodeOptions struct {
+	indent    int
+	sequences SequenceStyle
+	width     int
+	quote     QuoteStyle
+	sortKeys  bool
+	flowItems int
+}
+
+// WithIndent sets the number of spaces per indentation level, from 2 to 9.
+// The default is 2.
+func WithIndent(spaces int) EncodeOption {
+	return func(o *encodeOptions) { o.indent = spaces }
+}
+
+// WithSequenceStyle sets how sequences nested in mappings are indented.
+func WithSequenceStyle(style SequenceStyle) EncodeOption {
+	return func(o *encodeOptions) { o.sequences = style }
+}
+
+// WithLineWidth folds long plain and quoted strings at spaces so that lines
+// stay within width columns where possible. Zero, the default, never folds.
+func WithLineWidth(width int) EncodeOption {
+	return func(o *encodeOptions) { o.width = width }
+}
+
+// WithQuoteStyle sets how string values are quoted; keys and strings that
+// need a block style are not affected.
+func WithQuoteStyle(style QuoteStyle) EncodeOption {
+	return func(o *encodeOptions) { o.quote = style }
+}
+
+// WithSortedKeys sorts the keys of all mappings. By default struct fields
+// keep their declaration order (map keys are always sorted).
+func WithSortedKeys() EncodeOption {
+	return func(o *encodeOptions) { o.sortKeys = true }
+}
+
+// WithFlowSequences writes sequences of at most maxItems scalars in flow
+// style, e.g. `ports: [80, 443]`, as long as they fit within the line width.
+func WithFlowSequences(maxItems int) EncodeOption {
+	return func(o *encodeOptions) { o.flowItems = maxItems }
+}
+
+// EncodeWithOptions encodes v to YAML bytes laid out according to opts.
+// v may also be a *yaml.Node, whose comments, anchors and tags are kept.
+func EncodeWithOptions(v interface{}, opts ...EncodeOption) ([]byte, error) {
+	o := &encodeOptions{indent: 2}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.indent < 2 || o.indent > 9 {
+		return nil, fmt.Errorf("yamlutil: indent must be between 2 and 9, got %d", o.indent)
+	}
+	// The styles set below are applied to a copy, never to a node passed
+	// by the caller.
+	var node *yaml.Node
+	switch n := v.(type) {
+	case *yaml.Node:
+		node = copyNode(n, make(map[*yaml.Node]*yaml.Node))
+	case yaml.Node:
+		node = copyNode(&n, make(map[*yaml.Node]*yaml.Node))
+	default:
+		node = &yaml.Node{}
+		if err := node.Encode(v); err != nil {
+			return nil, err
+		}
+	}
+	e := &emitter{encodeOptions: o, flow: make(map[*yaml.Node]bool)}
+	e.prepare(node, false)
+	if err := e.document(node); err != nil {
+		return nil, err
+	}
+	return e.buf.Bytes(), nil
+}
+
+// emitter writes block collections itself so that it controls indentation
+// and folding, and leaves scalars and flow collections to yaml.v3.
+type emitter struct {
+	*encodeOptions
+	buf  bytes.Buffer
+	flow map[*yaml.Node]bool // sequences switched to flow style by prepare
+}
+
+// prepare applies the key order, quoting and flow options to the tree.
+func (e *emitter) prepare(n *yaml.Node, isKey bool) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			e.prepare(c, false)
+		}
+	case yaml.MappingNode:
+		if e.sortKeys {
+			sortPairs(n)
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			e.prepare(n.Content[i], true)
+			e.prepare(n.Content[i+1], false)
+		}
+	case yaml.SequenceNode:
+		scalars := len(n.Content) > 0 && len(n.Content) <= e.flowItems && n.Style&yaml.FlowStyle == 0
+		for _, c := range n.Content {
+			e.prepare(c, false)
+			scalars = scalars && c.Kind == yaml.ScalarNode && !hasComments(c)
+		}
+		if scalars {
+			n.Style |= yaml.FlowStyle
+			e.flow[n] = true
+		}
+	case yaml.ScalarNode:
+		if isKey || e.quote == QuoteAuto || n.ShortTag() != "!!str" || n.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 ||
+			strings.Contains(n.Value, "\n") {
+			break
+		}
+		n.Style &^= yaml.SingleQuotedStyle | yaml.DoubleQuotedStyle
+		if e.quote == QuoteSingle {
+			// yaml.v3 falls back to double quotes for strings that cannot be single quoted.
+			n.Style |= yaml.SingleQuotedStyle
+		} else {
+			n.Style |= yaml.DoubleQuotedStyle
+		}
+	}
+}
+
+// copyNode deep copies n, pointing aliases to the copies of their anchors.
+func copyNode(n *yaml.Node, copies map[*yaml.Node]*yaml.Node) *yaml.Node {
+	if n == nil {
+		return nil
+	}
+	if c, ok := copies[n]; ok {
+		return c
+	}
+	c := *n
+	copies[n] = &c
+	c.Alias = copyNode(n.Alias, copies)
+	if n.Content != nil {
+		c.Content = make([]*yaml.Node, len(n.Content))
+		for i, child := range n.Content {
+			c.Content[i] = copyNode(child, copies)
+		}
+	}
+	return &c
+}
+
+// sortPairs sorts the key/value pairs of a mapping by key.
+func sortPairs(n *yaml.Node) {
+	pairs := make([][2]*yaml.Node, len(n.Content)/2)
+	for i := range pairs {
+		pairs[i] = [2]*yaml.Node{n.Content[2*i], n.Content[2*i+1]}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i][0].Value < pairs[j][0].Value })
+	for i, p := range pairs {
+		n.Content[2*i], n.Content[2*i+1] = p[0], p[1]
+	}
+}
+
+func hasComments(n *yaml.Node) bool {
+	return n.HeadComment != "" || n.LineComment != "" || n.FootComment != ""
+}
+
+func (e *emitter) document(n *yaml.Node) error {
+	if n.Kind == yaml.DocumentNode {
+		// Document comments are separated from the content by a blank line,
+		// otherwise yaml.v3 would attach them to the first or last key.
+		if n.HeadComment != "" {
+			e.comment(n.HeadComment+"\n", 0)
+		}
+		for _, c := range n.Content {
+			if err := e.document(c); err != nil {
+				return err
+			}
+		}
+		if n.FootComment != "" {
+			e.comment("\n"+n.FootComment, 0)
+		}
+		return nil
+	}
+	if e.isBlock(n, 0) {
+		e.comment(n.HeadComment, 0)
+		if props := properties(n); props != "" {
+			e.buf.WriteString(props + "\n")
+		}
+		if err := e.block(n, 0, false); err != nil {
+			return err
+		}
+		e.comment(n.FootComment, 0)
+		return nil
+	}
+	text, err := e.render(n, 0)
+	if err != nil {
+		return err
+	}
+	e.comment(n.HeadComment, 0)
+	e.buf.WriteString(text)
+	e.lineComment(n.LineComment)
+	e.comment(n.FootComment, 0)
+	return nil
+}
+
+// isBlock reports whether n is written as a block collection when it
+// starts at column.
+func (e *emitter) isBlock(n *yaml.Node, column int) bool {
+	if (n.Kind != yaml.MappingNode && n.Kind != yaml.SequenceNode) || len(n.Content) == 0 {
+		return false
+	}
+	if n.Style&yaml.FlowStyle == 0 {
+		return true
+	}
+	if !e.flow[n] || e.width <= 0 {
+		return false
+	}
+	// Sequences switched to flow style fall back to block style when too wide.
+	text, err := e.render(n, 0)
+	return err == nil && column+len(text) > e.width
+}
+
+// block writes the block collection n at indent. With inline set the
+// indentation of the first line has been written already, after "- ".
+func (e *emitter) block(n *yaml.Node, indent int, inline bool) error {
+	if n.Kind == yaml.SequenceNode {
+		for i, item := range n.Content {
+			inline = e.entryHead(item.HeadComment, indent, inline && i == 0)
+			e.buf.WriteString("-")
+			if err := e.value(item, indent, indent+2, item.LineComment, true); err != nil {
+				return err
+			}
+			e.comment(item.FootComment, indent)
+		}
+	} else {
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			inline = e.entryHead(key.HeadComment, indent, inline && i == 0)
+			text, err := e.key(key)
+			if err != nil {
+				return err
+			}
+			e.buf.WriteString(text + ":")
+			childIndent := indent + e.indent
+			if value.Kind == yaml.SequenceNode && e.sequences == SequenceCompact {
+				childIndent = indent
+			}
+			if err := e.value(value, indent, childIndent, joinComments(key.LineComment, value.LineComment), false); err != nil {
+				return err
+			}
+			e.comment(key.FootComment, indent)
+			e.comment(value.FootComment, indent)
+		}
+	}
+	return nil
+}
+
+// entryHead writes the head comment and indentation of a mapping or
+// sequence entry and reports whether the entry still continues a line.
+func (e *emitter) entryHead(comment string, indent int, inline bool) bool {
+	if comment != "" && inline {
+		// Comments cannot sit between "- " and the first key; move the
+		// collection to the next line.
+		e.buf.Truncate(e.buf.Len() - 1)
+		e.buf.WriteByte('\n')
+		inline = false
+	}
+	e.comment(comment, indent)
+	if !inline {
+		e.buf.WriteString(strings.Repeat(" ", indent))
+	}
+	return false
+}
+
+// value writes the value of an entry after "key:" or "-". indent is the
+// indentation of the entry and childIndent that of a nested block.
+func (e *emitter) value(n *yaml.Node, indent, childIndent int, comment string, item bool) error {
+	column := e.column() + 1
+	if !e.isBlock(n, column) {
+		text, err := e.render(n, indent)
+		if err != nil {
+			return err
+		}
+		if text != "" {
+			e.buf.WriteString(" " + e.fold(n, text, column, indent+e.indent))
+		}
+		e.lineComment(comment)
+		return nil
+	}
+	props := properties(n)
+	if props != "" {
+		e.buf.WriteString(" " + props)
+	}
+	if item && props == "" && comment == "" && n.HeadComment == "" {
+		// Block collections start right after "- ".
+		e.buf.WriteString(" ")
+		return e.block(n, indent+2, true)
+	}
+	e.lineComment(comment)
+	if item {
+		childIndent = indent + 2
+	}
+	e.comment(n.HeadComment, childIndent)
+	return e.block(n, childIndent, false)
+}
+
+// properties returns the anchor and tag of a block collection.
+func properties(n *yaml.Node) string {
+	var props []string
+	if n.Anchor != "" {
+		props = append(props, "&"+n.Anchor)
+	}
+	if n.Tag != "" && (n.Style&yaml.TaggedStyle != 0 || !strings.HasPrefix(n.Tag, "!!")) {
+		props = append(props, n.Tag)
+	}
+	return strings.Join(props, " ")
+}
+
+// key renders a mapping key on a single line.
+func (e *emitter) key(n *yaml.Node) (string, error) {
+	if n.Kind == yaml.ScalarNode && n.ShortTag() == "!!merge" {
+		// yaml.v3 writes merge keys as "!!merge <<".
+		return n.Value, nil
+	}
+	text, err := e.render(n, 0)
+	if err == nil && strings.Contains(text, "\n") {
+		quoted := *n
+		quoted.Style = yaml.DoubleQuotedStyle | n.Style&yaml.TaggedStyle
+		if n.Kind != yaml.ScalarNode {
+			quoted.Style = yaml.FlowStyle
+		}
+		return e.render(&quoted, 0)
+	}
+	return text, err
+}
+
+// render formats a scalar, alias or flow collection with yaml.v3. Lines of
+// block scalars after the first are indented for a parent at indent.
+func (e *emitter) render(n *yaml.Node, indent int) (string, error) {
+	bare := stripComments(n)
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(e.indent)
+	if err := enc.Encode(bare); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	text := strings.TrimSuffix(buf.String(), "\n")
+	if indent > 0 && strings.Contains(text, "\n") {
+		lines := strings.Split(text, "\n")
+		for i := 1; i < len(lines); i++ {
+			if lines[i] != "" {
+				lines[i] = strings.Repeat(" ", indent) + lines[i]
+			}
+		}
+		text = strings.Join(lines, "\n")
+	}
+	return text, nil
+}
+
+// stripComments returns a copy of n without comments, which the caller
+// writes itself.
+func stripComments(n *yaml.Node) *yaml.Node {
+	c := *n
+	c.HeadComment, c.LineComment, c.FootComment = "", "", ""
+	if len(n.Content) > 0 {
+		c.Content = make([]*yaml.Node, len(n.Content))
+		for i, child := range n.Content {
+			c.Content[i] = stripComments(child)
+		}
+	}
+	return &c
+}
+
+// fold breaks a long single-line plain or quoted scalar at spaces, so that
+// lines stay within the line width, continuing at indent.
+func (e *emitter) fold(n *yaml.Node, text string, column, indent int) string {
+	if e.width <= 0 || n.Kind != yaml.ScalarNode || column+len(text) <= e.width || strings.Contains(text, "\n") ||
+		n.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 || n.Anchor != "" || strings.HasPrefix(text, "!") {
+		return text
+	}
+	var b strings.Builder
+	start := 0 // start of the current line in text
+	lastBreak := -1
+	for i := 1; i < len(text)-1; i++ {
+		// A single space between two other characters folds into a line
+		// break. Lines are never continued with an indicator such as '#',
+		// which would start a comment.
+		if text[i] != ' ' || text[i-1] == ' ' || text[i-1] == '\\' || text[i+1] == ' ' || strings.IndexByte(indicators, text[i+1]) >= 0 {
+			continue
+		}
+		if column+i-start > e.width && lastBreak > start {
+			b.WriteString(text[start:lastBreak] + "\n" + strings.Repeat(" ", indent))
+			start, column = lastBreak+1, indent
+		}
+		lastBreak = i
+	}
+	if column+len(text)-start > e.width && lastBreak > start {
+		b.WriteString(text[start:lastBreak] + "\n" + strings.Repeat(" ", indent))
+		start = lastBreak + 1
+	}
+	b.WriteString(text[start:])
+	return b.String()
+}
+
+// indicators are the characters with a special meaning at the start of a plain scalar.
+const indicators = "-?:,[]{}#&*!|>'\"%@`"
+
+// column returns the column the next byte is written at.
+func (e *emitter) column() int {
+	data := e.buf.Bytes()
+	return len(data) - 1 - bytes.LastIndexByte(data, '\n')
+}
+
+func (e *emitter) comment(comment string, indent int) {
+	if comment == "" {
+		return
+	}
+	if e.column() > 0 {
+		e.buf.WriteByte('\n')
+	}
+	for _, line := range strings.Split(comment, "\n") {
+		if line != "" {
+			e.buf.WriteString(strings.Repeat(" ", indent) + line)
+		}
+		e.buf.WriteByte('\n')
+	}
+}
+
+func (e *emitter) lineComment(comment string) {
+	if comment != "" {
+		e.buf.WriteString(" " + comment)
+	}
+	e.buf.WriteByte('\n')
+}
+
+func joinComments(a, b string) string {
+	if a == "" || b == "" {
+		return a + b
+	}
+	return a + " " + b
+}
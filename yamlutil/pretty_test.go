@@ -0,0 +1,145 @@
+package yamlutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+type prettyService struct {
+	Name        string            `yaml:"name"`
+	Image       string            `yaml:"image"`
+	Ports       []int             `yaml:"ports"`
+	Description string            `yaml:"description,omitempty"`
+	Env         map[string]string `yaml:"env,omitempty"`
+	Volumes     []prettyVolume    `yaml:"volumes,omitempty"`
+}
+
+type prettyVolume struct {
+	Source string `yaml:"source"`
+	Target string `yaml:"target"`
+}
+
+func TestEncodeWithOptions(t *testing.T) {
+	svc := prettyService{
+		Name:    "web",
+		Image:   "nginx:1.25",
+		Ports:   []int{80, 443},
+		Env:     map[string]string{"B": "2", "A": "true"},
+		Volumes: []prettyVolume{{Source: "/data", Target: "/var/www"}},
+	}
+
+	out, err := EncodeWithOptions(svc)
+	assert.NoError(t, err)
+	assert.Equal(t, `name: web
+image: nginx:1.25
+ports:
+  - 80
+  - 443
+env:
+  A: "true"
+  B: "2"
+volumes:
+  - source: /data
+    target: /var/www
+`, string(out))
+
+	out, err = EncodeWithOptions(svc, WithIndent(4), WithSequenceStyle(SequenceCompact), WithSortedKeys(),
+		WithQuoteStyle(QuoteSingle), WithFlowSequences(3))
+	assert.NoError(t, err)
+	assert.Equal(t, `env:
+    A: 'true'
+    B: '2'
+image: 'nginx:1.25'
+name: 'web'
+ports: [80, 443]
+volumes:
+- source: '/data'
+  target: '/var/www'
+`, string(out))
+
+	// The decoded value is the same whatever the layout.
+	var decoded prettyService
+	assert.NoError(t, yaml.Unmarshal(out, &decoded))
+	assert.Equal(t, svc, decoded)
+
+	_, err = EncodeWithOptions(svc, WithIndent(1))
+	assert.Error(t, err)
+}
+
+func TestEncodeWithOptionsLineWidth(t *testing.T) {
+	svc := prettyService{
+		Name:        "web",
+		Ports:       []int{8080, 8081, 8082, 8083, 8084, 8085},
+		Description: "serves the static pages of the public website # and nothing else, behind the load balancer",
+	}
+	out, err := EncodeWithOptions(svc, WithLineWidth(40), WithFlowSequences(10))
+	assert.NoError(t, err)
+	assert.Equal(t, `name: web
+image: ""
+ports:
+  - 8080
+  - 8081
+  - 8082
+  - 8083
+  - 8084
+  - 8085
+description: 'serves the static pages of
+  the public website # and nothing else,
+  behind the load balancer'
+`, string(out))
+	var decoded prettyService
+	assert.NoError(t, yaml.Unmarshal(out, &decoded))
+	assert.Equal(t, svc, decoded)
+
+	out, err = EncodeWithOptions(svc, WithLineWidth(40), WithQuoteStyle(QuoteDouble))
+	assert.NoError(t, err)
+	decoded = prettyService{}
+	assert.NoError(t, yaml.Unmarshal(out, &decoded))
+	assert.Equal(t, svc, decoded)
+}
+
+func TestEncodeWithOptionsNode(t *testing.T) {
+	src := `# service definition
+name: web # the name
+base: &base
+  replicas: 2
+  labels:
+    - a
+    - b
+tiers:
+  - name: |
+      multi
+      line
+    <<: *base
+`
+	var doc yaml.Node
+	assert.NoError(t, yaml.Unmarshal([]byte(src), &doc))
+	out, err := EncodeWithOptions(&doc, WithSequenceStyle(SequenceCompact))
+	assert.NoError(t, err)
+	assert.Equal(t, `# service definition
+name: web # the name
+base: &base
+  replicas: 2
+  labels:
+  - a
+  - b
+tiers:
+- name: |
+    multi
+    line
+  <<: *base
+`, string(out))
+
+	var before, after interface{}
+	assert.NoError(t, yaml.Unmarshal([]byte(src), &before))
+	assert.NoError(t, yaml.Unmarshal(out, &after))
+	assert.Equal(t, before, after)
+}
+
+func TestEncodePretty(t *testing.T) {
+	out, err := EncodePretty(prettyService{Name: "web", Image: "nginx", Ports: []int{80}})
+	assert.NoError(t, err)
+	assert.Equal(t, "name: web\nimage: nginx\nports: [80]\n", string(out))
+}
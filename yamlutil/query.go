@@ -0,0 +1,468 @@
+package yamlutil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Match is a value selected by Query.
+type Match struct {
+	Document int         // 0-based index of the document in the stream
+	Path     string      // concrete key path, e.g. "services[1].image"
+	Line     int         // 1-based line of the value
+	Column   int         // 1-based column of the value
+	Value    interface{} // the value decoded into generic Go values
+	Node     *yaml.Node
+}
+
+// Query selects values from every document of data with a JSONPath-like
+// expression and returns them in document order:
+//
+//	$.services[*].image        every image of the services list
+//	$..image                   every "image" key at any depth
+//	$.services[-1]             the last service
+//	$.services[1:3].name       names of the second and third services
+//	$.services[?(@.name == "api")].ports[0]
+//	$.metadata.labels["app.kubernetes.io/name"]
+//
+// The leading "$" is optional. Aliases and `<<` merge keys are followed.
+// A query matching nothing returns no matches and no error.
+func Query(data []byte, expr string) ([]Match, error) {
+	q, err := parseQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	var matches []Match
+	for i := 0; ; i++ {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return matches, nil
+		}
+		if err != nil {
+			return matches, err
+		}
+		if len(doc.Content) == 0 {
+			continue
+		}
+		found, err := q.eval(doc.Content[0], i)
+		if err != nil {
+			return matches, err
+		}
+		matches = append(matches, found...)
+	}
+}
+
+// Query selects values from the document like the Query function.
+func (d *Document) Query(expr string) ([]Match, error) {
+	q, err := parseQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+	if d.content() == nil {
+		return nil, nil
+	}
+	return q.eval(d.content(), 0)
+}
+
+type selectorKind int
+
+const (
+	selectKey selectorKind = iota
+	selectIndex
+	selectWildcard
+	selectSlice
+	selectFilter
+)
+
+// selector is one step of a query.
+type selector struct {
+	kind      selectorKind
+	recursive bool // preceded by ".."
+	key       string
+	index     int
+	start     *int // slice bounds, nil when omitted
+	end       *int
+	filter    *queryFilter
+}
+
+// queryFilter is the condition of a [?(...)] selector: the value at path
+// relative to the candidate exists, or compares to value.
+type queryFilter struct {
+	path  []pathSegment
+	op    string // "", "==" or "!="
+	value interface{}
+}
+
+type query []selector
+
+// queryNode is a node reached while evaluating a query.
+type queryNode struct {
+	node *yaml.Node
+	path []pathSegment
+}
+
+func (q query) eval(root *yaml.Node, document int) ([]Match, error) {
+	current := []queryNode{{node: root}}
+	for _, sel := range q {
+		var next []queryNode
+		for _, qn := range current {
+			candidates := []queryNode{qn}
+			if sel.recursive {
+				candidates = descendants(qn, nil)
+			}
+			for _, c := range candidates {
+				selected, err := sel.apply(c)
+				if err != nil {
+					return nil, err
+				}
+				next = append(next, selected...)
+			}
+		}
+		current = next
+	}
+	matches := make([]Match, len(current))
+	for i, qn := range current {
+		n := resolve(qn.node)
+		m := Match{Document: document, Path: joinSegments(qn.path), Line: n.Line, Column: n.Column, Node: n}
+		if err := n.Decode(&m.Value); err != nil {
+			return nil, fmt.Errorf("yamlutil: %s: %w", m.Path, err)
+		}
+		matches[i] = m
+	}
+	return matches, nil
+}
+
+// resolve follows aliases to the node they refer to.
+func resolve(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode && n.Alias != nil {
+		n = n.Alias
+	}
+	return n
+}
+
+// descendants returns qn and every node below it in document order.
+// Aliases are not descended into, but values merged with `<<` are visited
+// under each mapping merging them, like the other keys of the mapping.
+func descendants(qn queryNode, all []queryNode) []queryNode {
+	return visitDescendants(qn, all, make(map[*yaml.Node]bool))
+}
+
+// visitDescendants is descendants with active holding the nodes being
+// visited, which merge keys can lead back to.
+func visitDescendants(qn queryNode, all []queryNode, active map[*yaml.Node]bool) []queryNode {
+	active[qn.node] = true
+	defer delete(active, qn.node)
+	all = append(all, qn)
+	for _, c := range children(qn) {
+		if c.node.Kind != yaml.AliasNode && !active[c.node] {
+			all = visitDescendants(c, all, active)
+		}
+	}
+	return all
+}
+
+// children returns the values of a mapping, including merged keys not
+// overridden, or the items of a sequence.
+func children(qn queryNode) []queryNode {
+	n := resolve(qn.node)
+	var out []queryNode
+	switch n.Kind {
+	case yaml.SequenceNode:
+		for i, c := range n.Content {
+			out = append(out, queryNode{c, appendSegment(qn.path, pathSegment{index: i, isIndex: true})})
+		}
+	case yaml.MappingNode:
+		for _, e := range entries(n) {
+			out = append(out, queryNode{e[1], appendSegment(qn.path, pathSegment{key: e[0].Value})})
+		}
+	}
+	return out
+}
+
+// entries returns the key/value pairs of a mapping with `<<` merge keys
+// expanded; keys of the mapping itself take precedence.
+func entries(n *yaml.Node) [][2]*yaml.Node {
+	return mergedEntries(n, make(map[*yaml.Node]bool))
+}
+
+// mergedEntries is entries with merging holding the mappings being
+// expanded, so mappings merged into themselves are skipped.
+func mergedEntries(n *yaml.Node, merging map[*yaml.Node]bool) [][2]*yaml.Node {
+	merging[n] = true
+	defer delete(merging, n)
+	var pairs [][2]*yaml.Node
+	seen := make(map[string]bool)
+	var merged []*yaml.Node
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+		if key.ShortTag() == "!!merge" {
+			merged = append(merged, value)
+			continue
+		}
+		seen[key.Value] = true
+		pairs = append(pairs, [2]*yaml.Node{key, value})
+	}
+	for _, value := range merged {
+		value = resolve(value)
+		sources := []*yaml.Node{value}
+		if value.Kind == yaml.SequenceNode {
+			sources = value.Content
+		}
+		for _, src := range sources {
+			if src = resolve(src); src.Kind != yaml.MappingNode || merging[src] {
+				continue
+			}
+			for _, e := range mergedEntries(src, merging) {
+				if !seen[e[0].Value] {
+					seen[e[0].Value] = true
+					pairs = append(pairs, e)
+				}
+			}
+		}
+	}
+	return pairs
+}
+
+// appendSegment returns a copy of path with seg appended.
+func appendSegment(path []pathSegment, seg pathSegment) []pathSegment {
+	return append(append(make([]pathSegment, 0, len(path)+1), path...), seg)
+}
+
+// apply returns the nodes selected by s below qn.
+func (s selector) apply(qn queryNode) ([]queryNode, error) {
+	n := resolve(qn.node)
+	switch s.kind {
+	case selectKey:
+		if n.Kind != yaml.MappingNode {
+			return nil, nil
+		}
+		for _, e := range entries(n) {
+			if e[0].Value == s.key {
+				return []queryNode{{e[1], appendSegment(qn.path, pathSegment{key: s.key})}}, nil
+			}
+		}
+	case selectIndex:
+		if n.Kind != yaml.SequenceNode {
+			return nil, nil
+		}
+		i := s.index
+		if i < 0 {
+			i += len(n.Content)
+		}
+		if i >= 0 && i < len(n.Content) {
+			return []queryNode{{n.Content[i], appendSegment(qn.path, pathSegment{index: i, isIndex: true})}}, nil
+		}
+	case selectWildcard:
+		return children(qn), nil
+	case selectSlice:
+		if n.Kind != yaml.SequenceNode {
+			return nil, nil
+		}
+		start, end := sliceBound(s.start, 0, len(n.Content)), sliceBound(s.end, len(n.Content), len(n.Content))
+		items := children(qn)
+		if start >= end {
+			return nil, nil
+		}
+		return items[start:end], nil
+	case selectFilter:
+		var out []queryNode
+		for _, c := range children(qn) {
+			ok, err := s.filter.match(c.node)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				out = append(out, c)
+			}
+		}
+		return out, nil
+	}
+	return nil, nil
+}
+
+// sliceBound resolves a slice bound, counting negative bounds from the end.
+func sliceBound(bound *int, def, length int) int {
+	if bound == nil {
+		return def
+	}
+	i := *bound
+	if i < 0 {
+		i += length
+	}
+	if i < 0 {
+		return 0
+	}
+	if i > length {
+		return length
+	}
+	return i
+}
+
+func (f *queryFilter) match(n *yaml.Node) (bool, error) {
+	qn := queryNode{node: n}
+	for _, seg := range f.path {
+		sel := selector{kind: selectKey, key: seg.key}
+		if seg.isIndex {
+			sel = selector{kind: selectIndex, index: seg.index}
+		}
+		found, _ := sel.apply(qn)
+		if len(found) == 0 {
+			return false, nil
+		}
+		qn = found[0]
+	}
+	if f.op == "" {
+		return true, nil
+	}
+	var v interface{}
+	if err := resolve(qn.node).Decode(&v); err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(v, f.value) == (f.op == "=="), nil
+}
+
+// parseQuery parses a query expression.
+func parseQuery(expr string) (query, error) {
+	p := strings.TrimSpace(expr)
+	switch {
+	case strings.HasPrefix(p, "$"):
+		p = p[1:]
+	case p != "" && p[0] != '.' && p[0] != '[':
+		p = "." + p
+	}
+	var q query
+	for p != "" {
+		var sel selector
+		switch {
+		case strings.HasPrefix(p, ".."):
+			sel.recursive = true
+			p = p[2:]
+			if strings.HasPrefix(p, "[") {
+				break
+			}
+			fallthrough
+		case p[0] == '.':
+			if !sel.recursive {
+				p = p[1:]
+			}
+			end := strings.IndexAny(p, ".[")
+			if end < 0 {
+				end = len(p)
+			}
+			name := p[:end]
+			p = p[end:]
+			switch name {
+			case "":
+				return nil, fmt.Errorf("yamlutil: invalid query %q: missing key", expr)
+			case "*":
+				sel.kind = selectWildcard
+			default:
+				sel.kind, sel.key = selectKey, name
+			}
+			q = append(q, sel)
+			continue
+		case p[0] != '[':
+			return nil, fmt.Errorf("yamlutil: invalid query %q at %q", expr, p)
+		}
+		end, err := parseQueryBracket(p, &sel)
+		if err != nil {
+			return nil, fmt.Errorf("yamlutil: invalid query %q: %w", expr, err)
+		}
+		p = p[end:]
+		q = append(q, sel)
+	}
+	return q, nil
+}
+
+// parseQueryBracket parses the bracketed selector at the start of p into
+// sel and returns its length.
+func parseQueryBracket(p string, sel *selector) (int, error) {
+	if strings.HasPrefix(p, "[?(") {
+		end := strings.Index(p, ")]")
+		if end < 0 {
+			return 0, errors.New("unterminated filter")
+		}
+		filter, err := parseFilter(p[3:end])
+		if err != nil {
+			return 0, err
+		}
+		sel.kind, sel.filter = selectFilter, filter
+		return end + 2, nil
+	}
+	if strings.HasPrefix(p, `["`) || strings.HasPrefix(p, "['") {
+		end, seg, err := parseBracket(p, 0)
+		if err != nil {
+			return 0, err
+		}
+		sel.kind, sel.key = selectKey, seg.key
+		return end, nil
+	}
+	end := strings.IndexByte(p, ']')
+	if end < 0 {
+		return 0, errors.New("unterminated bracket")
+	}
+	inner := strings.TrimSpace(p[1:end])
+	switch {
+	case inner == "*":
+		sel.kind = selectWildcard
+	case strings.Contains(inner, ":"):
+		sel.kind = selectSlice
+		bounds := strings.SplitN(inner, ":", 2)
+		for i, b := range bounds {
+			if b = strings.TrimSpace(b); b == "" {
+				continue
+			}
+			n, err := strconv.Atoi(b)
+			if err != nil {
+				return 0, fmt.Errorf("invalid slice %q", inner)
+			}
+			if i == 0 {
+				sel.start = &n
+			} else {
+				sel.end = &n
+			}
+		}
+	default:
+		n, err := strconv.Atoi(inner)
+		if err != nil {
+			return 0, fmt.Errorf("invalid index %q", inner)
+		}
+		sel.kind, sel.index = selectIndex, n
+	}
+	return end + 1, nil
+}
+
+// parseFilter parses `@.key`, `@.key == value` or `@.key != value`; values
+// are YAML scalars such as 80, true or "api".
+func parseFilter(s string) (*queryFilter, error) {
+	f := &queryFilter{}
+	operand := s
+	for _, op := range []string{"==", "!="} {
+		if i := strings.Index(s, op); i >= 0 {
+			f.op, operand = op, s[:i]
+			if err := yaml.Unmarshal([]byte(s[i+2:]), &f.value); err != nil {
+				return nil, fmt.Errorf("invalid filter value %q", strings.TrimSpace(s[i+2:]))
+			}
+			break
+		}
+	}
+	operand = strings.TrimSpace(operand)
+	if !strings.HasPrefix(operand, "@") {
+		return nil, fmt.Errorf("filter %q must start with @", s)
+	}
+	path, err := parsePath(strings.TrimPrefix(operand[1:], "."))
+	if err != nil {
+		return nil, err
+	}
+	f.path = path
+	return f, nil
+}
@@ -0,0 +1,99 @@
+package yamlutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const queryStream = `defaults: &defaults
+  image: base:1
+services:
+  - name: api
+    image: api:1
+    ports: [80, 443]
+  - name: worker
+    <<: *defaults
+metadata:
+  labels:
+    app.kubernetes.io/name: web
+---
+services:
+  - name: db
+    image: postgres:16
+`
+
+// queryResults returns the document index, path and value of each match.
+func queryResults(t *testing.T, expr string) []interface{} {
+	matches, err := Query([]byte(queryStream), expr)
+	assert.NoError(t, err, expr)
+	var out []interface{}
+	for _, m := range matches {
+		out = append(out, m.Document, m.Path, m.Value)
+	}
+	return out
+}
+
+func TestQuery(t *testing.T) {
+	assert.Equal(t, []interface{}{
+		0, "services[0].image", "api:1",
+		0, "services[1].image", "base:1", // through the merge key
+		1, "services[0].image", "postgres:16",
+	}, queryResults(t, "$.services[*].image"))
+	assert.Equal(t, queryResults(t, "$.services[*].image"), queryResults(t, "services[*].image"))
+
+	assert.Equal(t, []interface{}{
+		0, "defaults.image", "base:1",
+		0, "services[0].image", "api:1",
+		0, "services[1].image", "base:1",
+		1, "services[0].image", "postgres:16",
+	}, queryResults(t, "$..image"))
+	assert.Equal(t, []interface{}{0, "services[1].name", "worker", 1, "services[0].name", "db"},
+		queryResults(t, "$.services[-1].name"))
+	assert.Equal(t, []interface{}{0, "services[1].name", "worker"}, queryResults(t, "$.services[1:].name"))
+	assert.Equal(t, []interface{}{0, "services[0].ports[1]", 443}, queryResults(t, `$.services[?(@.name == "api")].ports[1]`))
+	assert.Equal(t, []interface{}{0, "services[0].name", "api"}, queryResults(t, "$.services[?(@.ports)].name"))
+	assert.Equal(t, []interface{}{0, "services[1].name", "worker", 1, "services[0].name", "db"},
+		queryResults(t, "$.services[?(@.name != api)].name"))
+	assert.Equal(t, []interface{}{0, `metadata.labels["app.kubernetes.io/name"]`, "web"},
+		queryResults(t, `$.metadata.labels["app.kubernetes.io/name"]`))
+	assert.Equal(t, []interface{}{0, "services[0].ports", []interface{}{80, 443}}, queryResults(t, "$.services[0].ports"))
+	assert.Empty(t, queryResults(t, "$.services[5]"))
+	assert.Empty(t, queryResults(t, "$.missing.key"))
+
+	matches, err := Query([]byte(queryStream), "$.services[0].ports[1]")
+	assert.NoError(t, err)
+	assert.Equal(t, 6, matches[0].Line)
+	assert.Equal(t, 17, matches[0].Column)
+	assert.Equal(t, "443", matches[0].Node.Value)
+
+	for _, expr := range []string{"$.services[", "$..", "$.services[x]", "$.services[?(name)]", "$.a b[0"} {
+		_, err := Query([]byte(queryStream), expr)
+		assert.Error(t, err, expr)
+	}
+	_, err = Query([]byte("a: [1\n"), "$.a")
+	assert.Error(t, err)
+
+	// Merge keys leading back to a mapping being visited end the descent.
+	matches, err = Query([]byte("a: &a {k: {<<: *a}, v: 1}\n"), "$..v")
+	assert.NoError(t, err)
+	assert.Len(t, matches, 2)
+	assert.Equal(t, "a.v", matches[0].Path)
+	assert.Equal(t, "a.k.v", matches[1].Path)
+}
+
+func TestDocumentQuery(t *testing.T) {
+	d, err := ParseDocument([]byte(queryStream))
+	assert.NoError(t, err)
+	matches, err := d.Query("$.services[*].name")
+	assert.NoError(t, err)
+	assert.Len(t, matches, 2)
+	assert.Equal(t, "services[1].name", matches[1].Path)
+	assert.Equal(t, 7, matches[1].Line)
+
+	// Match paths can be fed back to the Document.
+	assert.NoError(t, d.Set(matches[1].Path, "jobs"))
+	got, err := d.Get("services[1].name")
+	assert.NoError(t, err)
+	assert.Equal(t, "jobs", got)
+}
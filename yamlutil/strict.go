@@ -0,0 +1,194 @@
+package yamlutil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DecodeError describes a problem found while decoding a YAML document,
+// positioned for operators: `config.yaml:12:3: unknown field "LogLevle"`.
+type DecodeError struct {
+	File   string // file name, empty when decoding bytes or readers
+	Line   int    // 1-based line, 0 if unknown
+	Column int    // 1-based column, 0 if unknown
+	Path   string // key path, e.g. "Logger.LogLevle" or "services[1].image"
+	Msg    string
+}
+
+func (e *DecodeError) Error() string {
+	var pos []string
+	if e.File != "" {
+		pos = append(pos, e.File)
+	}
+	if e.Line > 0 {
+		pos = append(pos, strconv.Itoa(e.Line))
+		if e.Column > 0 {
+			pos = append(pos, strconv.Itoa(e.Column))
+		}
+	}
+	if len(pos) == 0 {
+		return e.Msg
+	}
+	return strings.Join(pos, ":") + ": " + e.Msg
+}
+
+// DecodeErrors aggregates every problem found in a document by the strict
+// decoding functions.
+type DecodeErrors []*DecodeError
+
+func (e DecodeErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, de := range e {
+		msgs[i] = de.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// As lets errors.As extract the first *DecodeError.
+func (e DecodeErrors) As(target interface{}) bool {
+	if t, ok := target.(**DecodeError); ok && len(e) > 0 {
+		*t = e[0]
+		return true
+	}
+	return false
+}
+
+// DecodeStrict is Decode failing on keys that do not match a struct field
+// and on values of the wrong type. Errors are DecodeErrors.
+func DecodeStrict(bts []byte, ptr interface{}) error {
+	return decodeStrict("", bts, ptr)
+}
+
+// DecodeReaderStrict is DecodeReader with the checks of DecodeStrict.
+func DecodeReaderStrict(r io.Reader, ptr interface{}) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return decodeStrict("", data, ptr)
+}
+
+// ReadFileStrict is ReadFile with the checks of DecodeStrict; errors carry
+// the file name.
+func ReadFileStrict(filePath string, v interface{}) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	return decodeStrict(filePath, data, v)
+}
+
+var (
+	errorLineRe    = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+	unknownFieldRe = regexp.MustCompile(`^field (.*) not found in type \S+$`)
+)
+
+func decodeStrict(file string, data []byte, ptr interface{}) error {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return DecodeErrors{newDecodeError(file, err.Error())}
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	err := dec.Decode(ptr)
+	var typeErr *yaml.TypeError
+	switch {
+	case err == nil || err == io.EOF:
+		return nil
+	case !errors.As(err, &typeErr):
+		return DecodeErrors{newDecodeError(file, err.Error())}
+	}
+	positions := nodePositions(&root)
+	errs := make(DecodeErrors, len(typeErr.Errors))
+	for i, msg := range typeErr.Errors {
+		de := newDecodeError(file, msg)
+		if m := unknownFieldRe.FindStringSubmatch(de.Msg); m != nil {
+			de.Msg = fmt.Sprintf("unknown field %q", m[1])
+			de.locate(positions, m[1], true)
+		} else {
+			de.locate(positions, "", false)
+		}
+		errs[i] = de
+	}
+	return errs
+}
+
+// newDecodeError parses the line number out of a yaml.v3 error message.
+func newDecodeError(file, msg string) *DecodeError {
+	de := &DecodeError{File: file, Msg: strings.TrimPrefix(msg, "yaml: ")}
+	if m := errorLineRe.FindStringSubmatch(msg); m != nil {
+		de.Line, _ = strconv.Atoi(m[1])
+		de.Msg = m[2]
+	}
+	return de
+}
+
+// nodePosition is a node of the document together with its key path.
+type nodePosition struct {
+	node  *yaml.Node
+	path  string
+	isKey bool
+}
+
+// nodePositions lists the nodes of a document in order; aliases are not followed.
+func nodePositions(root *yaml.Node) []nodePosition {
+	var positions []nodePosition
+	var walk func(n *yaml.Node, path string)
+	walk = func(n *yaml.Node, path string) {
+		switch n.Kind {
+		case yaml.DocumentNode:
+			for _, c := range n.Content {
+				walk(c, path)
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				keyPath := joinKey(path, n.Content[i].Value)
+				positions = append(positions, nodePosition{n.Content[i], keyPath, true})
+				walk(n.Content[i+1], keyPath)
+			}
+		case yaml.SequenceNode:
+			for i, c := range n.Content {
+				walk(c, path+"["+strconv.Itoa(i)+"]")
+			}
+		default:
+			positions = append(positions, nodePosition{n, path, false})
+		}
+	}
+	walk(root, "")
+	return positions
+}
+
+// joinKey appends a mapping key to a key path.
+func joinKey(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// locate sets the column and path of the error from the node it is about:
+// the key named key on the error's line, or else the first value there.
+func (e *DecodeError) locate(positions []nodePosition, key string, isKey bool) {
+	var found *nodePosition
+	for i := range positions {
+		p := &positions[i]
+		if p.node.Line != e.Line || p.isKey != isKey || isKey && p.node.Value != key {
+			continue
+		}
+		found = p
+		break
+	}
+	if found == nil {
+		return
+	}
+	e.Column = found.node.Column
+	e.Path = found.path
+}
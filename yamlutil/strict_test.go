@@ -0,0 +1,75 @@
+package yamlutil
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type strictLogger struct {
+	LogLevel string `yaml:"LogLevel"`
+	MaxSize  int    `yaml:"MaxSize"`
+}
+
+type strictConfig struct {
+	Name     string         `yaml:"name"`
+	Logger   strictLogger   `yaml:"Logger"`
+	Services []strictLogger `yaml:"services"`
+}
+
+func TestDecodeStrict(t *testing.T) {
+	data := []byte(`name: app
+Logger:
+  LogLevle: debug
+  MaxSize: big
+services:
+  - LogLevel: info
+    Extra: true
+`)
+	// The lenient functions ignore unknown keys.
+	var lenient strictConfig
+	assert.Error(t, Decode(data, &lenient)) // MaxSize is still a type error
+	assert.Equal(t, "info", lenient.Services[0].LogLevel)
+
+	var cfg strictConfig
+	err := DecodeStrict(data, &cfg)
+	var errs DecodeErrors
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 3)
+	assert.Equal(t, &DecodeError{Line: 3, Column: 3, Path: "Logger.LogLevle", Msg: `unknown field "LogLevle"`}, errs[0])
+	assert.Equal(t, 4, errs[1].Line)
+	assert.Equal(t, 12, errs[1].Column)
+	assert.Equal(t, "Logger.MaxSize", errs[1].Path)
+	assert.Equal(t, "cannot unmarshal !!str `big` into int", errs[1].Msg)
+	assert.Equal(t, "services[0].Extra", errs[2].Path)
+	assert.Equal(t, `3:3: unknown field "LogLevle"; 4:12: cannot unmarshal !!str `+"`big`"+` into int; 7:5: unknown field "Extra"`, err.Error())
+
+	var first *DecodeError
+	assert.True(t, errors.As(err, &first))
+	assert.Equal(t, "Logger.LogLevle", first.Path)
+
+	assert.NoError(t, DecodeReaderStrict(bytes.NewBufferString("name: ok\n"), &cfg))
+	assert.Equal(t, "ok", cfg.Name)
+	assert.NoError(t, DecodeStrict(nil, &cfg))
+
+	err = DecodeStrict([]byte("name: a\n  bad: indent\n"), &cfg)
+	assert.True(t, errors.As(err, &first))
+	assert.Equal(t, 2, first.Line)
+	assert.Equal(t, "2: mapping values are not allowed in this context", err.Error())
+}
+
+func TestReadFileStrict(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("name: app\nLogger:\n  LogLevle: debug\n"), 0600))
+
+	var cfg strictConfig
+	assert.NoError(t, ReadFile(path, &cfg))
+	err := ReadFileStrict(path, &cfg)
+	assert.EqualError(t, err, path+`:3:3: unknown field "LogLevle"`)
+
+	assert.Error(t, ReadFileStrict(filepath.Join(t.TempDir(), "missing.yaml"), &cfg))
+}
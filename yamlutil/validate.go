@@ -0,0 +1,750 @@
+package yamlutil
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/mail"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Validate checks every document of data against a JSON Schema and reports
+// each violation as a *DecodeError positioned on the offending YAML value:
+// `12:11: must be one of "debug", "info"`. Errors are DecodeErrors.
+//
+// schema is JSON or YAML text, as []byte or string, or any value encoding to
+// a schema with encoding/json, like the *structutil.Schema of JSONSchema.
+// Supported keywords are type, enum, const, minimum, maximum,
+// exclusiveMinimum, exclusiveMaximum, multipleOf, minLength, maxLength,
+// pattern, format, contentEncoding, items, minItems, maxItems, uniqueItems,
+// properties, required, additionalProperties, patternProperties,
+// minProperties, maxProperties, allOf, anyOf, oneOf, not and $ref to a
+// location in the schema such as "#/$defs/Logger"; others are ignored.
+// Recursive aliases and excessive aliasing are reported as *DecodeError.
+func Validate(data []byte, schema interface{}) error {
+	return validate("", data, schema)
+}
+
+// ValidateFile is Validate for the file at filePath; errors carry the file
+// name.
+func ValidateFile(filePath string, schema interface{}) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	return validate(filePath, data, schema)
+}
+
+func validate(file string, data []byte, schema interface{}) error {
+	root, err := parseSchema(schema)
+	if err != nil {
+		return err
+	}
+	v := &validator{root: root, patterns: make(map[string]*regexp.Regexp)}
+	var all DecodeErrors
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc yaml.Node
+		if err := dec.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return newDecodeError(file, err.Error())
+		}
+		if len(doc.Content) == 0 {
+			continue
+		}
+		v.aliases = newAliasExpansion()
+		errs, err := v.document(doc.Content[0])
+		if err != nil {
+			var de *DecodeError
+			if errors.As(err, &de) {
+				de.File = file
+			}
+			return err
+		}
+		for _, e := range errs {
+			e.File = file
+			all = append(all, e)
+		}
+	}
+	if len(all) > 0 {
+		return all
+	}
+	return nil
+}
+
+// parseSchema returns schema as decoded JSON: maps, slices, strings,
+// numbers, booleans and nil.
+func parseSchema(schema interface{}) (interface{}, error) {
+	var text []byte
+	switch s := schema.(type) {
+	case []byte:
+		text = s
+	case string:
+		text = []byte(s)
+	default:
+		b, err := json.Marshal(schema)
+		if err != nil {
+			return nil, fmt.Errorf("yamlutil: schema: %w", err)
+		}
+		text = b
+	}
+	var root interface{}
+	if err := yaml.Unmarshal(text, &root); err != nil {
+		return nil, fmt.Errorf("yamlutil: schema: %w", err)
+	}
+	if root == nil {
+		return true, nil
+	}
+	return stringKeys(root)
+}
+
+// stringKeys converts the maps of a decoded YAML schema to
+// map[string]interface{}, as in JSON: `properties: {8080: ...}` names the
+// property "8080". Null keys are errors.
+func stringKeys(v interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, value := range t {
+			converted, err := stringKeys(value)
+			if err != nil {
+				return nil, err
+			}
+			t[k] = converted
+		}
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, value := range t {
+			if k == nil {
+				return nil, errors.New("yamlutil: schema: null key")
+			}
+			converted, err := stringKeys(value)
+			if err != nil {
+				return nil, err
+			}
+			m[fmt.Sprint(k)] = converted
+		}
+		return m, nil
+	case []interface{}:
+		for i, item := range t {
+			converted, err := stringKeys(item)
+			if err != nil {
+				return nil, err
+			}
+			t[i] = converted
+		}
+	}
+	return v, nil
+}
+
+// maxRefDepth bounds $ref chains that do not consume any of the document,
+// like a schema referring to itself.
+const maxRefDepth = 64
+
+type validator struct {
+	root     interface{}
+	patterns map[string]*regexp.Regexp
+	aliases  *aliasExpansion // of the document being validated
+}
+
+// document returns the violations of the root schema by the document n.
+func (v *validator) document(n *yaml.Node) (DecodeErrors, error) {
+	if _, err := v.aliases.enter(n, nil); err != nil {
+		return nil, err
+	}
+	defer v.aliases.leave(n)
+	return v.node(n, v.root, nil, 0)
+}
+
+// node returns the violations of schema s by n, found at path. The error is
+// for problems of the schema itself.
+func (v *validator) node(n *yaml.Node, s interface{}, path []pathSegment, refs int) (DecodeErrors, error) {
+	var errs DecodeErrors
+	fail := func(at *yaml.Node, format string, args ...interface{}) {
+		errs = append(errs, &DecodeError{Line: at.Line, Column: at.Column, Path: joinSegments(path),
+			Msg: fmt.Sprintf(format, args...)})
+	}
+	switch s := s.(type) {
+	case bool:
+		if !s {
+			fail(n, "not allowed")
+		}
+		return errs, nil
+	case map[string]interface{}:
+		at := n
+		n = resolve(n)
+		if ref, ok := s["$ref"].(string); ok {
+			if refs >= maxRefDepth {
+				return nil, fmt.Errorf("yamlutil: schema: $ref %q nested deeper than %d", ref, maxRefDepth)
+			}
+			target, err := v.ref(ref)
+			if err != nil {
+				return nil, err
+			}
+			sub, err := v.node(at, target, path, refs+1)
+			if err != nil {
+				return nil, err
+			}
+			errs = append(errs, sub...)
+		}
+		if t, ok := s["type"]; ok && !typeMatches(n, t) {
+			fail(at, "expected %s, got %s", typeNames(t), nodeType(n))
+			// The other keywords would only repeat the type mismatch.
+			return errs, nil
+		}
+		if allowed, ok := s["enum"].([]interface{}); ok {
+			found, err := v.oneOf(n, path, allowed)
+			if err != nil {
+				return nil, err
+			}
+			if !found {
+				names := make([]string, len(allowed))
+				for i, a := range allowed {
+					names[i] = jsonText(a)
+				}
+				fail(at, "must be one of %s", strings.Join(names, ", "))
+			}
+		}
+		if c, ok := s["const"]; ok {
+			found, err := v.oneOf(n, path, []interface{}{c})
+			if err != nil {
+				return nil, err
+			}
+			if !found {
+				fail(at, "must be %s", jsonText(c))
+			}
+		}
+		failAt := func(format string, args ...interface{}) { fail(at, format, args...) }
+		var err error
+		switch n.Kind {
+		case yaml.ScalarNode:
+			err = v.scalar(n, s, failAt)
+		case yaml.SequenceNode:
+			err = v.sequence(n, s, path, &errs, failAt)
+		case yaml.MappingNode:
+			err = v.mapping(n, s, path, &errs, failAt)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := v.combinations(at, s, path, refs, &errs, failAt); err != nil {
+			return nil, err
+		}
+		return errs, nil
+	}
+	return nil, fmt.Errorf("yamlutil: schema: %s is not a schema", jsonText(s))
+}
+
+func (v *validator) scalar(n *yaml.Node, s map[string]interface{}, fail func(string, ...interface{})) error {
+	if f, ok := numberValue(n); ok {
+		if min, ok := number(s["minimum"]); ok && f < min {
+			fail("must be >= %s", formatNumber(min))
+		}
+		if max, ok := number(s["maximum"]); ok && f > max {
+			fail("must be <= %s", formatNumber(max))
+		}
+		if min, ok := number(s["exclusiveMinimum"]); ok && f <= min {
+			fail("must be > %s", formatNumber(min))
+		}
+		if max, ok := number(s["exclusiveMaximum"]); ok && f >= max {
+			fail("must be < %s", formatNumber(max))
+		}
+		if m, ok := number(s["multipleOf"]); ok && m > 0 {
+			if q := f / m; math.Abs(q-math.Round(q)) > 1e-9 {
+				fail("must be a multiple of %s", formatNumber(m))
+			}
+		}
+	}
+	if !isString(n) {
+		return nil
+	}
+	length := utf8.RuneCountInString(n.Value)
+	if min, ok := number(s["minLength"]); ok && float64(length) < min {
+		fail("length must be >= %s", formatNumber(min))
+	}
+	if max, ok := number(s["maxLength"]); ok && float64(length) > max {
+		fail("length must be <= %s", formatNumber(max))
+	}
+	if p, ok := s["pattern"].(string); ok {
+		re, err := v.pattern(p)
+		if err != nil {
+			return err
+		}
+		if !re.MatchString(n.Value) {
+			fail("must match pattern %q", p)
+		}
+	}
+	if f, ok := s["format"].(string); ok && !checkFormat(f, n.Value) {
+		fail("must be a valid %s", f)
+	}
+	if e, ok := s["contentEncoding"].(string); ok && e == "base64" {
+		if _, err := base64.StdEncoding.DecodeString(n.Value); err != nil {
+			fail("must be base64 encoded")
+		}
+	}
+	return nil
+}
+
+func (v *validator) sequence(n *yaml.Node, s map[string]interface{}, path []pathSegment, errs *DecodeErrors, fail func(string, ...interface{})) error {
+	if min, ok := number(s["minItems"]); ok && float64(len(n.Content)) < min {
+		fail("must have at least %s items", formatNumber(min))
+	}
+	if max, ok := number(s["maxItems"]); ok && float64(len(n.Content)) > max {
+		fail("must have at most %s items", formatNumber(max))
+	}
+	if unique, _ := s["uniqueItems"].(bool); unique {
+		seen := make(map[string]int)
+		for i, item := range n.Content {
+			value, err := v.child(item, appendSegment(path, pathSegment{index: i, isIndex: true}))
+			if err != nil {
+				return err
+			}
+			key := jsonText(value)
+			if j, ok := seen[key]; ok {
+				fail("items %d and %d are equal", j, i)
+				break
+			}
+			seen[key] = i
+		}
+	}
+	items, ok := s["items"]
+	if !ok {
+		return nil
+	}
+	for i, item := range n.Content {
+		itemPath := appendSegment(path, pathSegment{index: i, isIndex: true})
+		if _, err := v.aliases.enter(item, itemPath); err != nil {
+			return err
+		}
+		// Descending into the document ends $ref chains.
+		sub, err := v.node(item, items, itemPath, 0)
+		v.aliases.leave(item)
+		if err != nil {
+			return err
+		}
+		*errs = append(*errs, sub...)
+	}
+	return nil
+}
+
+func (v *validator) mapping(n *yaml.Node, s map[string]interface{}, path []pathSegment, errs *DecodeErrors, fail func(string, ...interface{})) error {
+	pairs := entries(n)
+	if min, ok := number(s["minProperties"]); ok && float64(len(pairs)) < min {
+		fail("must have at least %s properties", formatNumber(min))
+	}
+	if max, ok := number(s["maxProperties"]); ok && float64(len(pairs)) > max {
+		fail("must have at most %s properties", formatNumber(max))
+	}
+	if required, ok := s["required"].([]interface{}); ok {
+		present := make(map[string]bool, len(pairs))
+		for _, e := range pairs {
+			present[e[0].Value] = true
+		}
+		for _, r := range required {
+			if name, ok := r.(string); ok && !present[name] {
+				fail("missing required property %q", name)
+			}
+		}
+	}
+	properties, _ := s["properties"].(map[string]interface{})
+	patternProperties, _ := s["patternProperties"].(map[string]interface{})
+	// Match patterns in a stable order, so errors are reported the same way
+	// on every run.
+	patterns := make([]string, 0, len(patternProperties))
+	for p := range patternProperties {
+		patterns = append(patterns, p)
+	}
+	sort.Strings(patterns)
+	additional, hasAdditional := s["additionalProperties"]
+	for _, e := range pairs {
+		key, value := e[0], e[1]
+		keyPath := appendSegment(path, pathSegment{key: key.Value})
+		var schemas []interface{}
+		if p, ok := properties[key.Value]; ok {
+			schemas = append(schemas, p)
+		}
+		for _, p := range patterns {
+			re, err := v.pattern(p)
+			if err != nil {
+				return err
+			}
+			if re.MatchString(key.Value) {
+				schemas = append(schemas, patternProperties[p])
+			}
+		}
+		if len(schemas) == 0 && hasAdditional {
+			if allowed, ok := additional.(bool); ok && !allowed {
+				*errs = append(*errs, &DecodeError{Line: key.Line, Column: key.Column, Path: joinSegments(keyPath),
+					Msg: fmt.Sprintf("unknown property %q", key.Value)})
+				continue
+			}
+			schemas = append(schemas, additional)
+		}
+		if len(schemas) == 0 {
+			continue
+		}
+		if _, err := v.aliases.enter(value, keyPath); err != nil {
+			return err
+		}
+		for _, sub := range schemas {
+			found, err := v.node(value, sub, keyPath, 0)
+			if err != nil {
+				v.aliases.leave(value)
+				return err
+			}
+			*errs = append(*errs, found...)
+		}
+		v.aliases.leave(value)
+	}
+	return nil
+}
+
+// combinations checks allOf, anyOf, oneOf and not.
+func (v *validator) combinations(n *yaml.Node, s map[string]interface{}, path []pathSegment, refs int, errs *DecodeErrors, fail func(string, ...interface{})) error {
+	if all, ok := s["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			found, err := v.node(n, sub, path, refs)
+			if err != nil {
+				return err
+			}
+			*errs = append(*errs, found...)
+		}
+	}
+	if anyOf, ok := s["anyOf"].([]interface{}); ok {
+		matched, first, err := v.matching(n, anyOf, path, refs)
+		if err != nil {
+			return err
+		}
+		switch {
+		case matched > 0:
+		case len(anyOf) == 1:
+			*errs = append(*errs, first...)
+		default:
+			fail("must match at least one schema of anyOf")
+		}
+	}
+	if oneOfs, ok := s["oneOf"].([]interface{}); ok {
+		matched, first, err := v.matching(n, oneOfs, path, refs)
+		if err != nil {
+			return err
+		}
+		switch {
+		case matched == 1:
+		case matched == 0 && len(oneOfs) == 1:
+			*errs = append(*errs, first...)
+		default:
+			fail("must match exactly one schema of oneOf, matches %d", matched)
+		}
+	}
+	if not, ok := s["not"]; ok {
+		found, err := v.node(n, not, path, refs)
+		if err != nil {
+			return err
+		}
+		if len(found) == 0 {
+			fail("must not match the schema of not")
+		}
+	}
+	return nil
+}
+
+// matching returns how many of schemas n matches and the violations of the
+// first one.
+func (v *validator) matching(n *yaml.Node, schemas []interface{}, path []pathSegment, refs int) (int, DecodeErrors, error) {
+	matched := 0
+	var first DecodeErrors
+	for i, sub := range schemas {
+		found, err := v.node(n, sub, path, refs)
+		if err != nil {
+			return 0, nil, err
+		}
+		if len(found) == 0 {
+			matched++
+		} else if i == 0 {
+			first = found
+		}
+	}
+	return matched, first, nil
+}
+
+// ref returns the schema at ref, a JSON pointer fragment into the root
+// schema like "#/$defs/Logger".
+func (v *validator) ref(ref string) (interface{}, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("yamlutil: schema: unsupported $ref %q", ref)
+	}
+	s := v.root
+	pointer := strings.TrimPrefix(ref, "#")
+	if pointer == "" {
+		return s, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("yamlutil: schema: unsupported $ref %q", ref)
+	}
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		switch t := s.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if s, ok = t[token]; !ok {
+				return nil, fmt.Errorf("yamlutil: schema: $ref %q not found", ref)
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(t) {
+				return nil, fmt.Errorf("yamlutil: schema: $ref %q not found", ref)
+			}
+			s = t[i]
+		default:
+			return nil, fmt.Errorf("yamlutil: schema: $ref %q not found", ref)
+		}
+	}
+	return s, nil
+}
+
+func (v *validator) pattern(p string) (*regexp.Regexp, error) {
+	if re, ok := v.patterns[p]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(p)
+	if err != nil {
+		return nil, fmt.Errorf("yamlutil: schema: pattern %q: %w", p, err)
+	}
+	v.patterns[p] = re
+	return re, nil
+}
+
+// nodeType returns the JSON type of n: null, boolean, integer, number,
+// string, array or object. Timestamps and binary values are strings.
+func nodeType(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.MappingNode:
+		return "object"
+	case yaml.SequenceNode:
+		return "array"
+	}
+	switch n.ShortTag() {
+	case "!!null":
+		return "null"
+	case "!!bool":
+		return "boolean"
+	case "!!int":
+		return "integer"
+	case "!!float":
+		if f, ok := numberValue(n); ok && f == math.Trunc(f) && !math.IsInf(f, 0) {
+			return "integer"
+		}
+		return "number"
+	}
+	return "string"
+}
+
+// typeMatches reports whether n has one of the types t, a type name or a
+// list of them.
+func typeMatches(n *yaml.Node, t interface{}) bool {
+	names, ok := t.([]interface{})
+	if !ok {
+		names = []interface{}{t}
+	}
+	actual := nodeType(n)
+	for _, name := range names {
+		if name == actual || name == "number" && actual == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+func typeNames(t interface{}) string {
+	names, ok := t.([]interface{})
+	if !ok {
+		return fmt.Sprint(t)
+	}
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprint(name)
+	}
+	return strings.Join(parts, " or ")
+}
+
+func isString(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode && nodeType(n) == "string"
+}
+
+// numberValue returns the value of an integer or float scalar.
+func numberValue(n *yaml.Node) (float64, bool) {
+	if n.Kind != yaml.ScalarNode {
+		return 0, false
+	}
+	switch n.ShortTag() {
+	case "!!int", "!!float":
+		var f float64
+		if err := n.Decode(&f); err != nil {
+			return 0, false
+		}
+		return f, true
+	}
+	return 0, false
+}
+
+// value returns n, found at path, decoded like encoding/json would decode
+// its JSON form, for comparisons with schema values.
+func (v *validator) value(n *yaml.Node, path []pathSegment) (interface{}, error) {
+	n = resolve(n)
+	switch n.Kind {
+	case yaml.MappingNode:
+		m := make(map[string]interface{})
+		for _, e := range entries(n) {
+			value, err := v.child(e[1], appendSegment(path, pathSegment{key: e[0].Value}))
+			if err != nil {
+				return nil, err
+			}
+			m[e[0].Value] = value
+		}
+		return m, nil
+	case yaml.SequenceNode:
+		items := make([]interface{}, len(n.Content))
+		for i, item := range n.Content {
+			value, err := v.child(item, appendSegment(path, pathSegment{index: i, isIndex: true}))
+			if err != nil {
+				return nil, err
+			}
+			items[i] = value
+		}
+		return items, nil
+	}
+	switch nodeType(n) {
+	case "null":
+		return nil, nil
+	case "boolean":
+		var b bool
+		_ = n.Decode(&b)
+		return b, nil
+	case "integer", "number":
+		f, _ := numberValue(n)
+		return f, nil
+	}
+	return n.Value, nil
+}
+
+// child is value for a node below the one being validated.
+func (v *validator) child(n *yaml.Node, path []pathSegment) (interface{}, error) {
+	if _, err := v.aliases.enter(n, path); err != nil {
+		return nil, err
+	}
+	defer v.aliases.leave(n)
+	return v.value(n, path)
+}
+
+// oneOf reports whether n, found at path, equals one of values.
+func (v *validator) oneOf(n *yaml.Node, path []pathSegment, values []interface{}) (bool, error) {
+	value, err := v.value(n, path)
+	if err != nil {
+		return false, err
+	}
+	actual := jsonText(value)
+	for _, value := range values {
+		if jsonText(normalize(value)) == actual {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// normalize converts the numbers of a decoded schema value to float64, as
+// value does.
+func normalize(value interface{}) interface{} {
+	switch t := value.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			m[k] = normalize(v)
+		}
+		return m
+	case []interface{}:
+		items := make([]interface{}, len(t))
+		for i, v := range t {
+			items[i] = normalize(v)
+		}
+		return items
+	}
+	if f, ok := number(value); ok {
+		return f
+	}
+	return value
+}
+
+// number returns the numeric value of a schema keyword.
+func number(value interface{}) (float64, bool) {
+	switch t := value.(type) {
+	case int:
+		return float64(t), true
+	case int64:
+		return float64(t), true
+	case uint64:
+		return float64(t), true
+	case float64:
+		return t, true
+	}
+	return 0, false
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// jsonText returns value as JSON, with object keys sorted.
+func jsonText(value interface{}) string {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(b)
+}
+
+var hostnamePattern = regexp.MustCompile(`^(?i)[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?(\.[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)*$`)
+
+// checkFormat reports whether s has format f; unknown formats always match.
+func checkFormat(f, s string) bool {
+	switch f {
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, s)
+		return err == nil
+	case "date":
+		_, err := time.Parse("2006-01-02", s)
+		return err == nil
+	case "time":
+		_, err := time.Parse("15:04:05Z07:00", s)
+		return err == nil
+	case "email":
+		addr, err := mail.ParseAddress(s)
+		return err == nil && addr.Address == s
+	case "uri":
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme != "" && (u.Host != "" || u.Opaque != "" || u.Path != "")
+	case "hostname":
+		return len(s) <= 253 && hostnamePattern.MatchString(s)
+	case "ipv4":
+		ip := net.ParseIP(s)
+		return ip != nil && ip.To4() != nil && strings.Contains(s, ".")
+	case "ipv6":
+		ip := net.ParseIP(s)
+		return ip != nil && strings.Contains(s, ":")
+	}
+	return true
+}
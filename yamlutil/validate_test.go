@@ -0,0 +1,184 @@
+package yamlutil
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/reggiepy/goutils/v2/structutil"
+	"github.com/stretchr/testify/assert"
+)
+
+const validateSchema = `
+type: object
+required: [name, port]
+additionalProperties: false
+properties:
+  name: {type: string, minLength: 2, pattern: "^[a-z]+$"}
+  port: {type: integer, minimum: 1, maximum: 65535}
+  level: {enum: [debug, info]}
+  admin: {type: string, format: email}
+  tags: {type: array, items: {type: string}, uniqueItems: true, maxItems: 3}
+  logger: {$ref: "#/$defs/logger"}
+  timeout: {anyOf: [{type: string}, {type: integer}]}
+$defs:
+  logger:
+    type: object
+    properties:
+      file: {type: string}
+      size: {type: number, exclusiveMinimum: 0}
+`
+
+func validationErrors(t *testing.T, err error) []string {
+	t.Helper()
+	var errs DecodeErrors
+	if !assert.True(t, errors.As(err, &errs), "%v", err) {
+		return nil
+	}
+	var msgs []string
+	for _, e := range errs {
+		msgs = append(msgs, e.Path+" "+e.Error())
+	}
+	return msgs
+}
+
+func TestValidate(t *testing.T) {
+	valid := `name: web
+port: 8080
+level: info
+admin: ops@example.com
+tags: [a, b]
+logger: {file: app.log, size: 1.5}
+timeout: 5s
+`
+	assert.NoError(t, Validate([]byte(valid), validateSchema))
+
+	invalid := `name: W
+port: 70000
+level: trace
+admin: nobody
+tags: [a, a, b, c]
+logger:
+  file: [app.log]
+  size: 0
+timeout: true
+extra: 1
+`
+	assert.Equal(t, []string{
+		`name 1:7: length must be >= 2`,
+		`name 1:7: must match pattern "^[a-z]+$"`,
+		`port 2:7: must be <= 65535`,
+		`level 3:8: must be one of "debug", "info"`,
+		`admin 4:8: must be a valid email`,
+		`tags 5:7: must have at most 3 items`,
+		`tags 5:7: items 0 and 1 are equal`,
+		`logger.file 7:9: expected string, got array`,
+		`logger.size 8:9: must be > 0`,
+		`timeout 9:10: must match at least one schema of anyOf`,
+		`extra 10:1: unknown property "extra"`,
+	}, validationErrors(t, Validate([]byte(invalid), validateSchema)))
+
+	assert.Equal(t, []string{
+		` 1:1: missing required property "port"`,
+		`name 1:7: expected string, got integer`,
+	}, validationErrors(t, Validate([]byte("name: 12\n"), validateSchema)))
+}
+
+func TestValidateAliasesAndDocuments(t *testing.T) {
+	data := `base: &base
+  port: 0
+name: a
+<<: *base
+---
+name: b
+port: x
+`
+	schema := map[string]interface{}{
+		"properties": map[string]interface{}{
+			"port": map[string]interface{}{"type": "integer", "minimum": 1},
+		},
+	}
+	// Inherited values are reported where they are written.
+	assert.Equal(t, []string{
+		`port 2:9: must be >= 1`,
+		`port 7:7: expected integer, got string`,
+	}, validationErrors(t, Validate([]byte(data), schema)))
+}
+
+func TestValidateStructSchema(t *testing.T) {
+	type logger struct {
+		Level string `yaml:"level" validate:"oneof=debug info"`
+		Port  int    `yaml:"port" validate:"min=1"`
+	}
+	schema, err := structutil.JSONSchema(&logger{}, structutil.WithSchemaTagName("yaml"))
+	assert.NoError(t, err)
+
+	assert.NoError(t, Validate([]byte("level: debug\nport: 9000\n"), schema))
+	assert.Equal(t, []string{
+		`level 1:8: must be one of "debug", "info"`,
+		`port 2:7: must be >= 1`,
+	}, validationErrors(t, Validate([]byte("level: warn\nport: 0\n"), schema)))
+}
+
+func TestValidateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("name: web\nport: -1\n"), 0644))
+
+	err := ValidateFile(path, validateSchema)
+	assert.EqualError(t, err, path+":2:7: must be >= 1")
+	var de *DecodeError
+	assert.True(t, errors.As(err, &de))
+	assert.Equal(t, path, de.File)
+	assert.Equal(t, "port", de.Path)
+}
+
+func TestValidateSchemaErrors(t *testing.T) {
+	err := Validate([]byte("a: 1\n"), `{"$ref": "#/$defs/missing"}`)
+	assert.EqualError(t, err, `yamlutil: schema: $ref "#/$defs/missing" not found`)
+
+	err = Validate([]byte("a: x\n"), `{"properties": {"a": {"pattern": "("}}}`)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `yamlutil: schema: pattern "("`)
+
+	err = Validate([]byte("a: 1\n"), `{"$ref": "#"}`)
+	assert.EqualError(t, err, `yamlutil: schema: $ref "#" nested deeper than 64`)
+
+	// Keys that are not strings in YAML schemas name properties like in JSON.
+	assert.EqualError(t, Validate([]byte("8080: 1\ntrue: x\n"), "properties: {8080: {type: string}, true: {type: integer}}"),
+		`1:7: expected string, got integer; 2:7: expected integer, got string`)
+	assert.EqualError(t, Validate([]byte("a: 1\n"), "properties: {~: {}}"), "yamlutil: schema: null key")
+
+	assert.Error(t, Validate([]byte("a: [\n"), true))
+	assert.NoError(t, Validate([]byte("a: 1\n"), `true`))
+	assert.EqualError(t, Validate([]byte("a: 1\n"), `false`), "1:1: not allowed")
+}
+
+func TestValidateAliasLimits(t *testing.T) {
+	unique := `{"properties": {"x": {"uniqueItems": true}}}`
+	err := Validate([]byte("x: &a [*a, 1]\n"), unique)
+	assert.EqualError(t, err, "1:8: alias *a refers to a value containing it")
+	var de *DecodeError
+	assert.True(t, errors.As(err, &de))
+	assert.Equal(t, "x[0]", de.Path)
+
+	err = Validate([]byte("x: &a {k: *a}\n"), `{"properties": {"x": {"enum": [1]}}}`)
+	assert.EqualError(t, err, "1:11: alias *a refers to a value containing it")
+	err = Validate([]byte("x: &a [*a]\n"), `{"additionalProperties": {"items": {"items": {}}}}`)
+	assert.EqualError(t, err, "1:8: alias *a refers to a value containing it")
+
+	// $ref chains end on each level of the document.
+	nested := `{"$ref": "#/$defs/n", "$defs": {"n": {"anyOf": [{"type": "integer"}, {"type": "array", "items": {"$ref": "#/$defs/n"}}]}}}`
+	data := strings.Repeat("[", 70) + "1" + strings.Repeat("]", 70)
+	assert.NoError(t, Validate([]byte(data), nested))
+	assert.Equal(t, []string{` 1:1: must match at least one schema of anyOf`},
+		validationErrors(t, Validate([]byte(strings.Replace(data, "1", "x", 1)), nested)))
+
+	path := filepath.Join(t.TempDir(), "bomb.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(aliasBomb(9)), 0644))
+	err = ValidateFile(path, `{"additionalProperties": {"$ref": "#/$defs/n"}, "$defs": {"n": {"items": {"$ref": "#/$defs/n"}}}}`)
+	assert.True(t, errors.As(err, &de))
+	assert.Equal(t, path, de.File)
+	assert.Equal(t, "document contains excessive aliasing", de.Msg)
+}
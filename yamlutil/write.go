@@ -0,0 +1,187 @@
+package yamlutil
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// WriteOption configures WriteFile.
+type WriteOption func(o *writeOptions)
+
+type writeOptions struct {
+	mode     os.FileMode
+	modeSet  bool
+	atomic   bool
+	preserve bool
+	backup   string      // suffix of the backup file, empty for no backup
+	dirMode  os.FileMode // mode of created parent directories, 0 to not create them
+}
+
+// WithAtomicWrite writes to a temporary file in the same directory, syncs
+// it and renames it over the target, so readers and crashes never see a
+// partly written file.
+func WithAtomicWrite() WriteOption {
+	return func(o *writeOptions) {
+		o.atomic = true
+	}
+}
+
+// WithFileMode sets the permissions of the file, 0644 by default. New files
+// are created with the mode less the umask, as by os.WriteFile; existing
+// files keep their permissions unless this option is given.
+func WithFileMode(mode os.FileMode) WriteOption {
+	return func(o *writeOptions) {
+		o.mode, o.modeSet = mode.Perm(), true
+	}
+}
+
+// WithPreserveMode keeps the permissions and, where the process may change
+// it, the owner of an existing file, even with WithFileMode or
+// WithAtomicWrite; WithFileMode then only applies to new files.
+func WithPreserveMode() WriteOption {
+	return func(o *writeOptions) {
+		o.preserve = true
+	}
+}
+
+// WithBackup copies the previous content of the file to the file name with
+// suffix appended, ".bak" when suffix is empty.
+func WithBackup(suffix string) WriteOption {
+	return func(o *writeOptions) {
+		if suffix == "" {
+			suffix = ".bak"
+		}
+		o.backup = suffix
+	}
+}
+
+// WithParentDirs creates missing parent directories with mode.
+func WithParentDirs(mode os.FileMode) WriteOption {
+	return func(o *writeOptions) {
+		o.dirMode = mode.Perm()
+	}
+}
+
+// writeFile writes data to filePath as configured by opts.
+func writeFile(filePath string, data []byte, opts []WriteOption) error {
+	o := &writeOptions{mode: 0644}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.dirMode != 0 {
+		if err := os.MkdirAll(filepath.Dir(filePath), o.dirMode); err != nil {
+			return err
+		}
+	}
+	// Write through symbolic links rather than replacing them.
+	if target, err := filepath.EvalSymlinks(filePath); err == nil {
+		filePath = target
+	}
+	info, err := os.Stat(filePath)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		info = nil
+	case err != nil:
+		return err
+	case o.backup != "":
+		if err := backupFile(filePath, filePath+o.backup, info); err != nil {
+			return err
+		}
+	}
+
+	mode := o.mode
+	if info != nil && (o.preserve || !o.modeSet) {
+		mode = info.Mode().Perm()
+	}
+	if !o.atomic {
+		if err := os.WriteFile(filePath, data, mode); err != nil {
+			return err
+		}
+		if info != nil && mode != info.Mode().Perm() {
+			// os.WriteFile only applies the mode to new files.
+			return os.Chmod(filePath, mode)
+		}
+		return nil
+	}
+	return writeAtomic(filePath, data, mode, info, o.preserve)
+}
+
+// writeAtomic replaces filePath with a temporary file holding data. The
+// mode of new files is subject to the umask, like with os.WriteFile.
+func writeAtomic(filePath string, data []byte, mode os.FileMode, old os.FileInfo, preserve bool) (err error) {
+	dir, base := filepath.Split(filePath)
+	if dir == "" {
+		dir = "."
+	}
+	tmp, err := createTemp(dir, base, mode)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+	if _, err = tmp.Write(data); err != nil {
+		return err
+	}
+	if old != nil {
+		if err = tmp.Chmod(mode); err != nil {
+			return err
+		}
+		if preserve {
+			chown(tmp, old)
+		}
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), filePath); err != nil {
+		return err
+	}
+	syncDir(dir)
+	return nil
+}
+
+// createTemp creates a new file in dir named after base with mode, less the
+// umask, unlike os.CreateTemp which always uses 0600.
+func createTemp(dir, base string, mode os.FileMode) (*os.File, error) {
+	for i := 0; ; i++ {
+		suffix := strconv.FormatInt(time.Now().UnixNano(), 36) + strconv.Itoa(os.Getpid()) + strconv.Itoa(i)
+		f, err := os.OpenFile(filepath.Join(dir, "."+base+"."+suffix+".tmp"), os.O_RDWR|os.O_CREATE|os.O_EXCL, mode)
+		if errors.Is(err, fs.ErrExist) && i < 100 {
+			continue
+		}
+		return f, err
+	}
+}
+
+// syncDir makes a rename in dir durable where the platform supports it.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	_ = d.Close()
+}
+
+// backupFile copies the file at src, described by info, to dst.
+func backupFile(src, dst string, info os.FileInfo) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(dst, data, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chmod(dst, info.Mode().Perm())
+}
@@ -0,0 +1,88 @@
+package yamlutil
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func fileMode(t *testing.T, path string) os.FileMode {
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	return info.Mode().Perm()
+}
+
+func readString(t *testing.T, path string) string {
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	return string(data)
+}
+
+func TestWriteFileOptions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not supported on Windows")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "conf", "app.yaml")
+
+	// Parent directories are only created on request.
+	assert.Error(t, WriteFile(path, map[string]int{"a": 1}))
+	assert.NoError(t, WriteFile(path, map[string]int{"a": 1}, WithParentDirs(0700), WithFileMode(0600)))
+	assert.Equal(t, "a: 1\n", readString(t, path))
+	assert.Equal(t, os.FileMode(0600), fileMode(t, path))
+	assert.Equal(t, os.FileMode(0700), fileMode(t, filepath.Dir(path)))
+
+	// Existing files keep their mode unless one is given.
+	assert.NoError(t, os.Chmod(path, 0640))
+	assert.NoError(t, WriteFile(path, map[string]int{"a": 2}, WithAtomicWrite()))
+	assert.Equal(t, "a: 2\n", readString(t, path))
+	assert.Equal(t, os.FileMode(0640), fileMode(t, path))
+	assert.NoError(t, WriteFile(path, map[string]int{"a": 3}, WithFileMode(0600)))
+	assert.Equal(t, os.FileMode(0600), fileMode(t, path))
+	assert.NoError(t, os.Chmod(path, 0640))
+	assert.NoError(t, WriteFile(path, map[string]int{"a": 4}, WithAtomicWrite(), WithFileMode(0600), WithPreserveMode()))
+	assert.Equal(t, os.FileMode(0640), fileMode(t, path))
+
+	// Backups hold the previous content.
+	assert.NoError(t, WriteFile(path, map[string]int{"a": 5}, WithAtomicWrite(), WithBackup("")))
+	assert.Equal(t, "a: 4\n", readString(t, path+".bak"))
+	assert.Equal(t, os.FileMode(0640), fileMode(t, path+".bak"))
+	assert.NoError(t, WriteFile(path, map[string]int{"a": 6}, WithBackup("~")))
+	assert.Equal(t, "a: 5\n", readString(t, path+"~"))
+
+	// No temporary files are left behind, and symbolic links are kept.
+	link := filepath.Join(dir, "link.yaml")
+	assert.NoError(t, os.Symlink(path, link))
+	assert.NoError(t, WriteFile(link, map[string]int{"a": 7}, WithAtomicWrite()))
+	assert.Equal(t, "a: 7\n", readString(t, path))
+	info, err := os.Lstat(link)
+	assert.NoError(t, err)
+	assert.Equal(t, os.ModeSymlink, info.Mode()&os.ModeSymlink)
+	entries, err := os.ReadDir(filepath.Dir(path))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 3) // app.yaml, app.yaml.bak and app.yaml~
+
+	assert.Error(t, WriteFile(filepath.Join(dir, "missing", "x.yaml"), 1, WithAtomicWrite()))
+
+	// New files get 0644 less the umask either way.
+	ref := filepath.Join(dir, "ref")
+	assert.NoError(t, os.WriteFile(ref, nil, 0644))
+	assert.NoError(t, WriteFile(filepath.Join(dir, "plain.yaml"), 1))
+	assert.NoError(t, WriteFile(filepath.Join(dir, "atomic.yaml"), 1, WithAtomicWrite()))
+	assert.Equal(t, fileMode(t, ref), fileMode(t, filepath.Join(dir, "plain.yaml")))
+	assert.Equal(t, fileMode(t, ref), fileMode(t, filepath.Join(dir, "atomic.yaml")))
+}
+
+func TestDocumentSaveOptions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("a: 1 # one\n"), 0600))
+	d, err := LoadDocument(path)
+	assert.NoError(t, err)
+	assert.NoError(t, d.Set("a", 2))
+	assert.NoError(t, d.Save(WithAtomicWrite(), WithBackup(".orig")))
+	assert.Equal(t, "a: 2 # one\n", readString(t, path))
+	assert.Equal(t, "a: 1 # one\n", readString(t, path+".orig"))
+}
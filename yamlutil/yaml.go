@@ -6,13 +6,16 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-// WriteFile writes data to a YAML file.
-func WriteFile(filePath string, data interface{}) error {
+// WriteFile writes data to a YAML file. By default new files get mode
+// 0644 less the umask and existing files are truncated and rewritten in
+// place; see the WriteOption functions for atomic writes, permissions and
+// backups.
+func WriteFile(filePath string, data interface{}, opts ...WriteOption) error {
 	yamlBytes, err := yaml.Marshal(data)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(filePath, yamlBytes, 0664)
+	return writeFile(filePath, yamlBytes, opts)
 }
 
 // ReadFile reads YAML file data into the provided struct.